@@ -2,35 +2,82 @@ package cgroups
 
 import (
 	"MyDocker/cgroups/subsystems"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
 )
 
-type CgroupManager struct {
-	Path     string                     // cgroup在hierarchy中的路径，相当于创建的cgroup目录相对于root cgroup的路径
-	Resource *subsystems.ResourceConfig //资源配置
+var freezer = &subsystems.FreezerSubsystem{}
+
+// Manager 管理容器的cgroup，根据系统的cgroup版本选择实现
+type Manager interface {
+	Apply(pid int) error                                           // 将进程加入到cgroup中
+	Set(res *subsystems.ResourceConfig) error                      // 设置cgroup资源限制
+	Destory() error                                                // 释放cgroup
+	Freeze() error                                                 // 冻结cgroup中的所有进程
+	Thaw() error                                                   // 恢复cgroup中被冻结的进程
+	GetStats() (*Stats, error)                                     // 读取cgroup中的资源使用情况
+	NotifyOOM() (*OOMNotifier, error)                              // 监听cgroup中发生的OOM
+	NotifyPressure(alert PressureAlert) (*PressureNotifier, error) // 监听资源 stall 时间超过告警阈值
 }
 
-func NewCgroupManager(path string) *CgroupManager {
+// NewCgroupManager returns the cgroup v2 manager if the unified hierarchy is mounted
+// at the cgroup root, otherwise the manager of v1 hierarchies
+func NewCgroupManager(path string) Manager {
+	if subsystems.IsCgroup2UnifiedMode() {
+		return &CgroupV2Manager{
+			Path: path,
+		}
+	}
 	return &CgroupManager{
 		Path: path,
 	}
 }
 
-// 将进程pid加入到cgroup中
+// CgroupManager 管理cgroup v1 各个hierarchy中的cgroup
+type CgroupManager struct {
+	Path     string                     // cgroup在hierarchy中的路径，相当于创建的cgroup目录相对于root cgroup的路径
+	Resource *subsystems.ResourceConfig //资源配置
+}
+
+// 将进程pid加入到cgroup中，任何一个hierarchy失败时返回错误，没有挂载的hierarchy跳过
 func (c *CgroupManager) Apply(pid int) error {
 	for _, subSysIns := range subsystems.SubsystemIns {
-		subSysIns.Apply(c.Path, pid)
+		if !mounted(subSysIns) {
+			continue
+		}
+		if err := subSysIns.Apply(c.Path, pid); err != nil {
+			return fmt.Errorf("apply %s cgroup: %v", subSysIns.Name(), err)
+		}
 	}
 	return nil
 }
 
-// 设置cgroup资源限制
+// 设置cgroup资源限制，任何一个限制无法生效时返回错误
 func (c *CgroupManager) Set(res *subsystems.ResourceConfig) error {
 	for _, subSysIns := range subsystems.SubsystemIns {
-		subSysIns.Set(c.Path, res)
+		// 没有设置限制的 subsystem 在 host 上不存在时不影响容器运行
+		if !mounted(subSysIns) && !res.Requires(subSysIns.Name()) {
+			log.Warnf("cgroup subsystem %s is not mounted, skip it", subSysIns.Name())
+			continue
+		}
+		if err := subSysIns.Set(c.Path, res); err != nil {
+			return fmt.Errorf("set %s cgroup: %v", subSysIns.Name(), err)
+		}
 	}
 	return nil
 }
 
+// mounted reports whether the hierarchy of the subsystem is mounted, freezer can also
+// use cgroup v2
+func mounted(subSysIns subsystems.Subsystem) bool {
+	if subsystems.FindCgroupMountPoint(subSysIns.Name()) != "" {
+		return true
+	}
+	_, isFreezer := subSysIns.(*subsystems.FreezerSubsystem)
+	return isFreezer && subsystems.FindCgroup2MountPoint() != ""
+}
+
 // release cgroup
 func (c *CgroupManager) Destory() error {
 	for _, subSysIns := range subsystems.SubsystemIns {
@@ -38,3 +85,13 @@ func (c *CgroupManager) Destory() error {
 	}
 	return nil
 }
+
+// 冻结cgroup中的所有进程
+func (c *CgroupManager) Freeze() error {
+	return freezer.Freeze(c.Path)
+}
+
+// 恢复cgroup中被冻结的进程
+func (c *CgroupManager) Thaw() error {
+	return freezer.Thaw(c.Path)
+}
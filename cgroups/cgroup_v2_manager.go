@@ -0,0 +1,108 @@
+package cgroups
+
+import (
+	"MyDocker/cgroups/subsystems"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// CgroupV2Manager 管理cgroup v2 unified hierarchy中的cgroup，所有controller共用同一个目录
+type CgroupV2Manager struct {
+	Path     string                     // cgroup相对于unified hierarchy根目录的路径
+	Resource *subsystems.ResourceConfig //资源配置
+}
+
+// 将进程pid加入到cgroup中
+func (c *CgroupV2Manager) Apply(pid int) error {
+	cgroupDir, err := c.getCgroupDir(false)
+	if err != nil {
+		return err
+	}
+	return subsystems.ApplyProcess(cgroupDir, pid, "cgroup.threads")
+}
+
+// 设置cgroup资源限制，任何一个限制无法生效时返回错误
+func (c *CgroupV2Manager) Set(res *subsystems.ResourceConfig) error {
+	cgroupDir, err := c.getCgroupDir(true)
+	if err != nil {
+		return err
+	}
+	for _, subSysIns := range subsystems.SubsystemIns {
+		if err := subSysIns.SetUnified(cgroupDir, res); err != nil {
+			return fmt.Errorf("set %s cgroup: %v", subSysIns.Name(), err)
+		}
+	}
+	return nil
+}
+
+// release cgroup
+func (c *CgroupV2Manager) Destory() error {
+	cgroupRoot := subsystems.FindCgroup2MountPoint()
+	if cgroupRoot == "" {
+		return fmt.Errorf("cgroup2 is not mounted")
+	}
+	if err := os.Remove(path.Join(cgroupRoot, c.Path)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove cgroup fail %v", err)
+	}
+	return nil
+}
+
+// 冻结cgroup中的所有进程
+func (c *CgroupV2Manager) Freeze() error {
+	return freezer.Freeze(c.Path)
+}
+
+// 恢复cgroup中被冻结的进程
+func (c *CgroupV2Manager) Thaw() error {
+	return freezer.Thaw(c.Path)
+}
+
+// getCgroupDir returns the absolute path of the cgroup, when autoCreate is set the
+// missing cgroups are created and controllers are enabled down to the cgroup
+func (c *CgroupV2Manager) getCgroupDir(autoCreate bool) (string, error) {
+	cgroupRoot := subsystems.FindCgroup2MountPoint()
+	if cgroupRoot == "" {
+		return "", fmt.Errorf("cgroup2 is not mounted")
+	}
+	cgroupDir := path.Join(cgroupRoot, c.Path)
+	if _, err := os.Stat(cgroupDir); err == nil {
+		return cgroupDir, nil
+	} else if !autoCreate || !os.IsNotExist(err) {
+		return "", fmt.Errorf("cgroup path error %v", err)
+	}
+
+	// 只有父 cgroup 的 cgroup.subtree_control 中开启的 controller 才能在子 cgroup 中使用
+	current := cgroupRoot
+	for _, name := range strings.Split(strings.Trim(c.Path, "/"), "/") {
+		if err := enableControllers(current); err != nil {
+			return "", err
+		}
+		current = path.Join(current, name)
+		if err := os.Mkdir(current, 0755); err != nil && !os.IsExist(err) {
+			return "", fmt.Errorf("error create cgroup %v", err)
+		}
+	}
+	return cgroupDir, nil
+}
+
+// enableControllers enables every controller available in the cgroup for its children
+func enableControllers(cgroupDir string) error {
+	content, err := ioutil.ReadFile(path.Join(cgroupDir, "cgroup.controllers"))
+	if err != nil {
+		return fmt.Errorf("read cgroup controllers fail %v", err)
+	}
+	controllers := strings.Fields(string(content))
+	if len(controllers) == 0 {
+		return nil
+	}
+	for i := range controllers {
+		controllers[i] = "+" + controllers[i]
+	}
+	if err := ioutil.WriteFile(path.Join(cgroupDir, "cgroup.subtree_control"), []byte(strings.Join(controllers, " ")), 0644); err != nil {
+		return fmt.Errorf("enable cgroup controllers %v fail %v", controllers, err)
+	}
+	return nil
+}
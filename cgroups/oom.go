@@ -0,0 +1,108 @@
+package cgroups
+
+import (
+	"MyDocker/cgroups/subsystems"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"golang.org/x/sys/unix"
+)
+
+// OOMNotifier 在cgroup发生OOM时通过C发出通知，Close后C被关闭
+type OOMNotifier struct {
+	C     <-chan struct{}
+	files []*os.File
+}
+
+// Close stops watching OOM events
+func (n *OOMNotifier) Close() error {
+	for _, file := range n.files {
+		file.Close()
+	}
+	return nil
+}
+
+// newOOMNotifier notifies every time the event file is readable and isOOM reports an
+// OOM happened, files are closed by Close
+func newOOMNotifier(eventFile *os.File, isOOM func() bool, files ...*os.File) *OOMNotifier {
+	c := make(chan struct{}, 1)
+	go func() {
+		defer close(c)
+		// 足够读取 eventfd 的计数或多个 inotify 事件
+		buf := make([]byte, 4096)
+		for {
+			if _, err := eventFile.Read(buf); err != nil {
+				return
+			}
+			if !isOOM() {
+				continue
+			}
+			// 未处理的通知合并为一个
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return &OOMNotifier{C: c, files: append(files, eventFile)}
+}
+
+// 通过 cgroup.event_control 为 memory.oom_control 注册 eventfd，发生OOM时 eventfd 可读
+func (c *CgroupManager) NotifyOOM() (*OOMNotifier, error) {
+	memoryPath, err := subsystems.GetCgroupPath("memory", c.Path, false)
+	if err != nil {
+		return nil, err
+	}
+	oomControl, err := os.Open(path.Join(memoryPath, "memory.oom_control"))
+	if err != nil {
+		return nil, err
+	}
+	// 设置为非阻塞，Close 时正在进行的 Read 才能返回
+	efd, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		oomControl.Close()
+		return nil, fmt.Errorf("create eventfd fail %v", err)
+	}
+	eventFile := os.NewFile(uintptr(efd), "oom-event")
+	control := fmt.Sprintf("%d %d", efd, oomControl.Fd())
+	if err := ioutil.WriteFile(path.Join(memoryPath, "cgroup.event_control"), []byte(control), 0644); err != nil {
+		eventFile.Close()
+		oomControl.Close()
+		return nil, fmt.Errorf("register oom event fail %v", err)
+	}
+	return newOOMNotifier(eventFile, func() bool { return true }, oomControl), nil
+}
+
+// 通过 inotify 监听 memory.events，其中的 oom 计数增加时表示发生了OOM
+func (c *CgroupV2Manager) NotifyOOM() (*OOMNotifier, error) {
+	cgroupDir, err := c.getCgroupDir(false)
+	if err != nil {
+		return nil, err
+	}
+	eventsPath := path.Join(cgroupDir, "memory.events")
+	events, err := readKeyValueFile(eventsPath)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init fail %v", err)
+	}
+	if _, err := unix.InotifyAddWatch(fd, eventsPath, unix.IN_MODIFY); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("watch %s fail %v", eventsPath, err)
+	}
+	// 内存达到 high、max 等也会修改 memory.events
+	oomCount := events["oom"]
+	isOOM := func() bool {
+		events, err := readKeyValueFile(eventsPath)
+		if err != nil || events["oom"] == oomCount {
+			return false
+		}
+		oomCount = events["oom"]
+		return true
+	}
+	return newOOMNotifier(os.NewFile(uintptr(fd), "inotify"), isOOM), nil
+}
@@ -0,0 +1,63 @@
+package cgroups
+
+import (
+	"MyDocker/cgroups/subsystems"
+	"fmt"
+)
+
+// SetParent creates the cgroup parent shared by containers and sets its aggregate
+// memory, cpu and pids limits
+func SetParent(parent string, res *subsystems.ResourceConfig) error {
+	if subsystems.IsCgroup2UnifiedMode() {
+		cgroupDir, err := (&CgroupV2Manager{Path: parent}).getCgroupDir(true)
+		if err != nil {
+			return err
+		}
+		for _, subSysIns := range subsystems.ParentSubsystemIns {
+			if err := subSysIns.SetUnified(cgroupDir, res); err != nil {
+				return fmt.Errorf("set %s cgroup of parent %s: %v", subSysIns.Name(), parent, err)
+			}
+		}
+		return nil
+	}
+	for _, subSysIns := range subsystems.ParentSubsystemIns {
+		if err := subSysIns.Set(parent, res); err != nil {
+			return fmt.Errorf("set %s cgroup of parent %s: %v", subSysIns.Name(), parent, err)
+		}
+	}
+	return nil
+}
+
+// ParentExists reports whether the cgroup exists in any cgroup hierarchy
+func ParentExists(parent string) bool {
+	if subsystems.IsCgroup2UnifiedMode() {
+		_, err := (&CgroupV2Manager{Path: parent}).getCgroupDir(false)
+		return err == nil
+	}
+	for _, subSysIns := range subsystems.SubsystemIns {
+		if _, err := subsystems.GetCgroupPath(subSysIns.Name(), parent, false); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveParent removes the cgroups in order, the deepest one should be the first. It
+// stops at the first cgroup which still has children and returns the removed ones
+func RemoveParent(parents []string) []string {
+	var removed []string
+	for _, p := range parents {
+		if subsystems.IsCgroup2UnifiedMode() {
+			(&CgroupV2Manager{Path: p}).Destory()
+		} else {
+			for _, subSysIns := range subsystems.SubsystemIns {
+				subSysIns.Remove(p)
+			}
+		}
+		if ParentExists(p) {
+			break
+		}
+		removed = append(removed, p)
+	}
+	return removed
+}
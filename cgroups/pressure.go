@@ -0,0 +1,217 @@
+package cgroups
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// PSI trigger 统计 stall 时间的窗口，单位us，没有 CAP_SYS_RESOURCE 时必须为 2s 的整数倍
+const pressureWindow = 2000000
+
+// PSIData 是 *.pressure 文件中的一行
+type PSIData struct {
+	Avg10  float64 `json:"avg10"`  // 最近10s内 stall 时间的百分比
+	Avg60  float64 `json:"avg60"`  // 最近60s内 stall 时间的百分比
+	Avg300 float64 `json:"avg300"` // 最近300s内 stall 时间的百分比
+	Total  uint64  `json:"total"`  // 累计 stall 时间，单位us
+}
+
+// PSIStats 是一种资源的 pressure，some 为至少一个进程 stall，full 为所有进程同时 stall
+type PSIStats struct {
+	Some PSIData `json:"some"`
+	Full PSIData `json:"full"`
+}
+
+// Pressure 是cgroup v2 中 cpu、memory、io 的 PSI
+type Pressure struct {
+	Cpu    *PSIStats `json:"cpu,omitempty"`
+	Memory *PSIStats `json:"memory,omitempty"`
+	Io     *PSIStats `json:"io,omitempty"`
+}
+
+// PressureAlert 在一种资源的 stall 时间超过阈值时告警，例如 memory=some:10%
+type PressureAlert struct {
+	Resource string  `json:"resource"` // cpu、memory 或 io
+	Type     string  `json:"type"`     // some 或 full
+	Percent  float64 `json:"percent"`  // 每个窗口内 stall 时间的百分比
+}
+
+func (a PressureAlert) String() string {
+	return fmt.Sprintf("%s=%s:%s%%", a.Resource, a.Type, strconv.FormatFloat(a.Percent, 'f', -1, 64))
+}
+
+// trigger returns the PSI trigger written to the pressure file, "<type> <stall us> <window us>"
+func (a PressureAlert) trigger() string {
+	return fmt.Sprintf("%s %d %d", a.Type, uint64(a.Percent*pressureWindow/100), pressureWindow)
+}
+
+// ParsePressureAlert parses an alert in the form of <resource>=<some|full>:<percent>%
+func ParsePressureAlert(spec string) (*PressureAlert, error) {
+	kv := strings.SplitN(spec, "=", 2)
+	if len(kv) != 2 {
+		return nil, fmt.Errorf("invalid pressure alert %q, should be <resource>=<some|full>:<percent>%%", spec)
+	}
+	if kv[0] != "cpu" && kv[0] != "memory" && kv[0] != "io" {
+		return nil, fmt.Errorf("invalid pressure alert %q: resource must be cpu, memory or io", spec)
+	}
+	threshold := strings.SplitN(kv[1], ":", 2)
+	if len(threshold) != 2 || (threshold[0] != "some" && threshold[0] != "full") {
+		return nil, fmt.Errorf("invalid pressure alert %q, should be <resource>=<some|full>:<percent>%%", spec)
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(threshold[1], "%"), 64)
+	if err != nil || percent*pressureWindow/100 < 1 || percent > 100 {
+		return nil, fmt.Errorf("invalid pressure alert %q: percent must be between 0 and 100", spec)
+	}
+	return &PressureAlert{Resource: kv[0], Type: threshold[0], Percent: percent}, nil
+}
+
+// PressureNotifier 在 stall 时间超过告警阈值时通过C发出通知，Close后C被关闭
+type PressureNotifier struct {
+	C    <-chan struct{}
+	stop int // pipe 的写端，关闭后停止 poll
+}
+
+// Close stops watching the pressure
+func (n *PressureNotifier) Close() error {
+	return unix.Close(n.stop)
+}
+
+// newPressureNotifier registers the PSI trigger of alert in the cgroup, the kernel
+// notifies by POLLPRI at most once per window
+func newPressureNotifier(cgroupDir string, alert PressureAlert) (*PressureNotifier, error) {
+	pressurePath := path.Join(cgroupDir, alert.Resource+".pressure")
+	fd, err := unix.Open(pressurePath, unix.O_RDWR|unix.O_CLOEXEC|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s fail %v", pressurePath, err)
+	}
+	if _, err := unix.Write(fd, []byte(alert.trigger())); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("register pressure trigger %s fail %v", alert, err)
+	}
+	stopPipe := make([]int, 2)
+	if err := unix.Pipe2(stopPipe, unix.O_CLOEXEC); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("new pipe fail %v", err)
+	}
+
+	c := make(chan struct{}, 1)
+	go func() {
+		defer close(c)
+		defer unix.Close(fd)
+		defer unix.Close(stopPipe[0])
+		fds := []unix.PollFd{
+			{Fd: int32(fd), Events: unix.POLLPRI},
+			{Fd: int32(stopPipe[0]), Events: unix.POLLIN},
+		}
+		for {
+			if _, err := unix.Poll(fds, -1); err != nil {
+				if err == unix.EINTR {
+					continue
+				}
+				return
+			}
+			// 写端关闭或cgroup被删除
+			if fds[1].Revents != 0 || fds[0].Revents&unix.POLLERR != 0 {
+				return
+			}
+			if fds[0].Revents&unix.POLLPRI != 0 {
+				select {
+				case c <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return &PressureNotifier{C: c, stop: stopPipe[1]}, nil
+}
+
+// v1 中没有 PSI
+func (c *CgroupManager) NotifyPressure(alert PressureAlert) (*PressureNotifier, error) {
+	return nil, fmt.Errorf("pressure alert is only supported on cgroup v2")
+}
+
+func (c *CgroupV2Manager) NotifyPressure(alert PressureAlert) (*PressureNotifier, error) {
+	cgroupDir, err := c.getCgroupDir(false)
+	if err != nil {
+		return nil, err
+	}
+	return newPressureNotifier(cgroupDir, alert)
+}
+
+// readPressure reads PSI of the cgroup, it returns nil if PSI is disabled in the kernel
+func readPressure(cgroupDir string) (*Pressure, error) {
+	pressure := &Pressure{}
+	for _, item := range []struct {
+		resource string
+		stats    **PSIStats
+	}{
+		{"cpu", &pressure.Cpu},
+		{"memory", &pressure.Memory},
+		{"io", &pressure.Io},
+	} {
+		stats, err := readPSIFile(path.Join(cgroupDir, item.resource+".pressure"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		*item.stats = stats
+	}
+	return pressure, nil
+}
+
+// readPSIFile reads a pressure file, each line of which is like
+// "some avg10=0.00 avg60=0.00 avg300=0.00 total=0"
+func readPSIFile(filePath string) (*PSIStats, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats := &PSIStats{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var data *PSIData
+		switch fields[0] {
+		case "some":
+			data = &stats.Some
+		case "full":
+			data = &stats.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			var err error
+			switch kv[0] {
+			case "avg10":
+				data.Avg10, err = strconv.ParseFloat(kv[1], 64)
+			case "avg60":
+				data.Avg60, err = strconv.ParseFloat(kv[1], 64)
+			case "avg300":
+				data.Avg300, err = strconv.ParseFloat(kv[1], 64)
+			case "total":
+				data.Total, err = strconv.ParseUint(kv[1], 10, 64)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("parse %s failed: %v", filePath, err)
+			}
+		}
+	}
+	return stats, scanner.Err()
+}
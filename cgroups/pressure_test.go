@@ -0,0 +1,37 @@
+package cgroups
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func TestParsePressureAlert(t *testing.T) {
+	alert, err := ParsePressureAlert("memory=some:10%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert.String() != "memory=some:10%" || alert.trigger() != "some 200000 2000000" {
+		t.Errorf("unexpected alert %v with trigger %q", alert, alert.trigger())
+	}
+	for _, invalid := range []string{"memory", "disk=some:10%", "cpu=half:10%", "io=full", "io=full:0%", "io=full:101%", "io=full:x%"} {
+		if _, err := ParsePressureAlert(invalid); err == nil {
+			t.Errorf("expect error for pressure alert %q", invalid)
+		}
+	}
+}
+
+func TestReadPSIFile(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "memory.pressure")
+	content := "some avg10=1.50 avg60=0.30 avg300=0.00 total=1137\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=704\n"
+	if err := ioutil.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stats, err := readPSIFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Some.Avg10 != 1.5 || stats.Some.Avg60 != 0.3 || stats.Some.Total != 1137 || stats.Full.Total != 704 {
+		t.Errorf("unexpected psi %+v", stats)
+	}
+}
@@ -0,0 +1,214 @@
+package cgroups
+
+import (
+	"MyDocker/cgroups/subsystems"
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Stats 是从cgroup中读取的资源使用情况
+type Stats struct {
+	CpuUsage    uint64    `json:"cpuUsage"`           // 累计CPU使用时间，单位ns
+	MemoryUsage uint64    `json:"memoryUsage"`        // 内存使用量
+	MemoryLimit uint64    `json:"memoryLimit"`        // 内存限制，0表示不限制
+	Pids        uint64    `json:"pids"`               // 进程数
+	BlkioRead   uint64    `json:"blkioRead"`          // 块设备读取的字节数
+	BlkioWrite  uint64    `json:"blkioWrite"`         // 块设备写入的字节数
+	OOMKills    uint64    `json:"oomKills"`           // 被OOM killer杀死的进程数
+	Pressure    *Pressure `json:"pressure,omitempty"` // PSI，只有 cgroup v2 中有
+}
+
+// v1 中 memory.limit_in_bytes 不限制时的值，按页对齐的最大 int64
+const memoryUnlimited = math.MaxInt64 &^ 4095
+
+// 读取cgroup v1 各个hierarchy中的资源使用情况
+func (c *CgroupManager) GetStats() (*Stats, error) {
+	stats := &Stats{}
+	cpuacctPath, err := subsystems.GetCgroupPath("cpuacct", c.Path, false)
+	if err != nil {
+		return nil, err
+	}
+	if stats.CpuUsage, err = readUintFile(path.Join(cpuacctPath, "cpuacct.usage")); err != nil {
+		return nil, err
+	}
+
+	memoryPath, err := subsystems.GetCgroupPath("memory", c.Path, false)
+	if err != nil {
+		return nil, err
+	}
+	if stats.MemoryUsage, err = readUintFile(path.Join(memoryPath, "memory.usage_in_bytes")); err != nil {
+		return nil, err
+	}
+	if stats.MemoryLimit, err = readUintFile(path.Join(memoryPath, "memory.limit_in_bytes")); err != nil {
+		return nil, err
+	}
+	if stats.MemoryLimit >= memoryUnlimited {
+		stats.MemoryLimit = 0
+	}
+	// 4.13 之前的内核 memory.oom_control 中没有 oom_kill
+	oomControl, err := readKeyValueFile(path.Join(memoryPath, "memory.oom_control"))
+	if err != nil {
+		return nil, err
+	}
+	stats.OOMKills = oomControl["oom_kill"]
+
+	// 没有加入 pids、blkio cgroup 的容器不统计
+	if pidsPath, err := subsystems.GetCgroupPath("pids", c.Path, false); err == nil {
+		if stats.Pids, err = readUintFile(path.Join(pidsPath, "pids.current")); err != nil {
+			return nil, err
+		}
+	}
+	if blkioPath, err := subsystems.GetCgroupPath("blkio", c.Path, false); err == nil {
+		if stats.BlkioRead, stats.BlkioWrite, err = readBlkioServiceBytes(path.Join(blkioPath, "blkio.throttle.io_service_bytes")); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// 读取cgroup v2 中的资源使用情况
+func (c *CgroupV2Manager) GetStats() (*Stats, error) {
+	cgroupDir, err := c.getCgroupDir(false)
+	if err != nil {
+		return nil, err
+	}
+	stats := &Stats{}
+	cpuStat, err := readKeyValueFile(path.Join(cgroupDir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	stats.CpuUsage = cpuStat["usage_usec"] * 1000
+
+	if stats.MemoryUsage, err = readUintFile(path.Join(cgroupDir, "memory.current")); err != nil {
+		return nil, err
+	}
+	if stats.MemoryLimit, err = readUintFile(path.Join(cgroupDir, "memory.max")); err != nil {
+		return nil, err
+	}
+	memoryEvents, err := readKeyValueFile(path.Join(cgroupDir, "memory.events"))
+	if err != nil {
+		return nil, err
+	}
+	stats.OOMKills = memoryEvents["oom_kill"]
+
+	// 父 cgroup 没有开启 pids、io controller 时不统计
+	if stats.Pids, err = readUintFile(path.Join(cgroupDir, "pids.current")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if stats.BlkioRead, stats.BlkioWrite, err = readIoStat(path.Join(cgroupDir, "io.stat")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if stats.Pressure, err = readPressure(cgroupDir); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// readUintFile reads a file holding a single number, "max" is read as 0
+func readUintFile(filePath string) (uint64, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "max" {
+		return 0, nil
+	}
+	num, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s failed: %v", filePath, err)
+	}
+	return num, nil
+}
+
+// readKeyValueFile reads a file of "key value" lines such as cpu.stat
+func readKeyValueFile(filePath string) (map[string]uint64, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if num, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			values[fields[0]] = num
+		}
+	}
+	return values, scanner.Err()
+}
+
+// readBlkioServiceBytes sums the read and write bytes of all devices, each line of the
+// file is like "8:0 Read 4096"
+func readBlkioServiceBytes(filePath string) (read, write uint64, err error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+		num, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch fields[1] {
+		case "Read":
+			read += num
+		case "Write":
+			write += num
+		}
+	}
+	return read, write, scanner.Err()
+}
+
+// readIoStat sums the read and write bytes of all devices, each line of the file is
+// like "8:0 rbytes=4096 wbytes=0 rios=1 wios=0 dbytes=0 dios=0"
+func readIoStat(filePath string) (read, write uint64, err error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			num, err := strconv.ParseUint(kv[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch kv[0] {
+			case "rbytes":
+				read += num
+			case "wbytes":
+				write += num
+			}
+		}
+	}
+	return read, write, scanner.Err()
+}
@@ -0,0 +1,179 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ThrottleDevice 是对一个块设备的读写速率限制
+type ThrottleDevice struct {
+	Major int64  `json:"major"`
+	Minor int64  `json:"minor"`
+	Rate  uint64 `json:"rate"` // bytes 或 IO 次数每秒
+}
+
+func (d ThrottleDevice) String() string {
+	return fmt.Sprintf("%d:%d %d", d.Major, d.Minor, d.Rate)
+}
+
+// BlkioSubsystem 限制cgroup对块设备的IO，v1 中为 blkio controller，v2 中为 io controller
+type BlkioSubsystem struct {
+}
+
+func (s *BlkioSubsystem) Name() string {
+	return "blkio"
+}
+
+func (s *BlkioSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
+		if res.BlkioWeight != 0 {
+			// 使用 BFQ 调度器时权重位于 blkio.bfq.weight
+			weightFile := path.Join(subsysCgroupPath, "blkio.weight")
+			if _, err := os.Stat(weightFile); os.IsNotExist(err) {
+				weightFile = path.Join(subsysCgroupPath, "blkio.bfq.weight")
+			}
+			// blkio.bfq.weight 读取时为 "default $WEIGHT" 以及各个设备的权重
+			weight := strconv.Itoa(int(res.BlkioWeight))
+			if err := writeVerified(weightFile, weight, blkioWeightAccepted(weight)); err != nil {
+				return fmt.Errorf("set cgroup blkio weight fail %v", err)
+			}
+		}
+		throttles := []struct {
+			file    string
+			devices []ThrottleDevice
+		}{
+			{"blkio.throttle.read_bps_device", res.BlkioDeviceReadBps},
+			{"blkio.throttle.write_bps_device", res.BlkioDeviceWriteBps},
+			{"blkio.throttle.read_iops_device", res.BlkioDeviceReadIOps},
+			{"blkio.throttle.write_iops_device", res.BlkioDeviceWriteIOps},
+		}
+		for _, throttle := range throttles {
+			// 每次只能写入一个设备的限制
+			for _, device := range throttle.devices {
+				if err := writeVerified(path.Join(subsysCgroupPath, throttle.file), device.String(), throttleAccepted(device, containsLine(device.String()))); err != nil {
+					return fmt.Errorf("set cgroup %s fail %v", throttle.file, err)
+				}
+			}
+		}
+		return nil
+	} else {
+		return err
+	}
+}
+
+func (s *BlkioSubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	if res.BlkioWeight != 0 {
+		weightFile := path.Join(cgroupDir, "io.weight")
+		weight := blkioWeightToIOWeight(res.BlkioWeight)
+		if _, err := os.Stat(weightFile); os.IsNotExist(err) {
+			// io.bfq.weight 与 blkio.weight 的取值范围相同
+			weightFile, weight = path.Join(cgroupDir, "io.bfq.weight"), uint64(res.BlkioWeight)
+		}
+		content := fmt.Sprintf("default %d", weight)
+		if err := writeVerified(weightFile, content, containsLine(content)); err != nil {
+			return fmt.Errorf("set cgroup io weight fail %v", err)
+		}
+	}
+	// io.max 每行为 "$MAJ:$MIN rbps=... wbps=... riops=... wiops=..."，可以只写部分 key
+	throttles := []struct {
+		key     string
+		devices []ThrottleDevice
+	}{
+		{"rbps", res.BlkioDeviceReadBps},
+		{"wbps", res.BlkioDeviceWriteBps},
+		{"riops", res.BlkioDeviceReadIOps},
+		{"wiops", res.BlkioDeviceWriteIOps},
+	}
+	for _, throttle := range throttles {
+		for _, device := range throttle.devices {
+			line := ioMaxLine(device, throttle.key)
+			if err := writeVerified(path.Join(cgroupDir, "io.max"), line, throttleAccepted(device, hasIoMax(device, throttle.key))); err != nil {
+				return fmt.Errorf("set cgroup io max fail %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// blkioWeightAccepted accepts blkio.weight with the weight or blkio.bfq.weight with the
+// default weight
+func blkioWeightAccepted(weight string) func(string) bool {
+	return func(actual string) bool {
+		return sameValue(weight)(actual) || containsLine("default "+weight)(actual)
+	}
+}
+
+// ioMaxLine formats the rate of key of the device as a line of io.max
+func ioMaxLine(device ThrottleDevice, key string) string {
+	return fmt.Sprintf("%d:%d %s=%d", device.Major, device.Minor, key, device.Rate)
+}
+
+// throttleAccepted skips the verification of a zero rate, which removes the limit of
+// the device
+func throttleAccepted(device ThrottleDevice, accepted func(string) bool) func(string) bool {
+	if device.Rate == 0 {
+		return func(string) bool { return true }
+	}
+	return accepted
+}
+
+// hasIoMax accepts io.max if the line of the device has the rate of key
+func hasIoMax(device ThrottleDevice, key string) func(string) bool {
+	return func(actual string) bool {
+		for _, line := range strings.Split(actual, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || fields[0] != fmt.Sprintf("%d:%d", device.Major, device.Minor) {
+				continue
+			}
+			for _, field := range fields[1:] {
+				if field == fmt.Sprintf("%s=%d", key, device.Rate) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// blkioWeightToIOWeight converts blkio.weight [10, 1000] of v1 to io.weight [1, 10000] of v2
+func blkioWeightToIOWeight(weight uint16) uint64 {
+	return 1 + (uint64(weight)-10)*9999/990
+}
+
+func (s *BlkioSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		return os.Remove(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+func (s *BlkioSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ApplyProcess(subsysCgroupPath, pid, "tasks"); err != nil {
+			return err
+		}
+		return nil
+	} else {
+		return fmt.Errorf("get cgroup %s error: %v", cgroupPath, err)
+	}
+}
+
+// NewThrottleDevice resolves the block device at devicePath to its major:minor number
+func NewThrottleDevice(devicePath string, rate uint64) (*ThrottleDevice, error) {
+	devType, major, minor, err := StatDevice(devicePath)
+	if err != nil {
+		return nil, err
+	}
+	if devType != 'b' {
+		return nil, fmt.Errorf("%s is not a block device", devicePath)
+	}
+	return &ThrottleDevice{
+		Major: major,
+		Minor: minor,
+		Rate:  rate,
+	}, nil
+}
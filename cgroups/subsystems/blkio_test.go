@@ -0,0 +1,65 @@
+package subsystems
+
+import "testing"
+
+func TestThrottleDeviceString(t *testing.T) {
+	cases := []struct {
+		device   ThrottleDevice
+		expected string
+	}{
+		{ThrottleDevice{Major: 8, Minor: 0, Rate: 1048576}, "8:0 1048576"},
+		{ThrottleDevice{Major: 253, Minor: 16, Rate: 100}, "253:16 100"},
+		{ThrottleDevice{Major: 8, Minor: 0, Rate: 0}, "8:0 0"},
+	}
+	for _, c := range cases {
+		if got := c.device.String(); got != c.expected {
+			t.Errorf("%+v.String() = %q, want %q", c.device, got, c.expected)
+		}
+	}
+}
+
+func TestIoMaxLine(t *testing.T) {
+	cases := []struct {
+		device   ThrottleDevice
+		key      string
+		expected string
+	}{
+		{ThrottleDevice{Major: 8, Minor: 0, Rate: 1048576}, "rbps", "8:0 rbps=1048576"},
+		{ThrottleDevice{Major: 8, Minor: 16, Rate: 2097152}, "wbps", "8:16 wbps=2097152"},
+		{ThrottleDevice{Major: 253, Minor: 0, Rate: 100}, "riops", "253:0 riops=100"},
+		{ThrottleDevice{Major: 253, Minor: 0, Rate: 0}, "wiops", "253:0 wiops=0"},
+	}
+	for _, c := range cases {
+		if got := ioMaxLine(c.device, c.key); got != c.expected {
+			t.Errorf("ioMaxLine(%+v, %s) = %q, want %q", c.device, c.key, got, c.expected)
+		}
+	}
+}
+
+func TestBlkioWeightToIOWeight(t *testing.T) {
+	cases := map[uint16]uint64{
+		10:   1,
+		100:  910,
+		500:  4950,
+		1000: 10000,
+	}
+	for weight, expected := range cases {
+		if got := blkioWeightToIOWeight(weight); got != expected {
+			t.Errorf("blkioWeightToIOWeight(%d) = %d, want %d", weight, got, expected)
+		}
+	}
+}
+
+func TestBlkioWeightAccepted(t *testing.T) {
+	cases := map[string]bool{
+		"500":                    true,
+		"default 500\n8:0 200\n": true,
+		"100":                    false,
+		"default 100\n8:0 500\n": false,
+	}
+	for actual, expected := range cases {
+		if got := blkioWeightAccepted("500")(actual); got != expected {
+			t.Errorf("blkioWeightAccepted(500)(%q) = %v, want %v", actual, got, expected)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package subsystems
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path"
 	"strconv"
@@ -20,16 +19,78 @@ func (s *CpuSubsystem) Name() string {
 func (s *CpuSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	if subsycCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
 		if res.CpuShare != "" {
-			if err := ioutil.WriteFile(path.Join(subsycCgroupPath, "cpu.shares"), []byte(res.CpuShare), 0644); err != nil {
+			if err := writeVerified(path.Join(subsycCgroupPath, "cpu.shares"), res.CpuShare, sameValue(res.CpuShare)); err != nil {
 				return fmt.Errorf("set cgroup cpu share fail %v", err)
 			}
 		}
+		// quota 不能超过 period 允许的范围，先设置 period
+		if res.CpuPeriod != 0 {
+			if err := writeInt(path.Join(subsycCgroupPath, "cpu.cfs_period_us"), int64(res.CpuPeriod)); err != nil {
+				return fmt.Errorf("set cgroup cpu period fail %v", err)
+			}
+		}
+		if res.CpuQuota != 0 {
+			if err := writeInt(path.Join(subsycCgroupPath, "cpu.cfs_quota_us"), res.CpuQuota); err != nil {
+				return fmt.Errorf("set cgroup cpu quota fail %v", err)
+			}
+		}
 		return nil
 	} else {
 		return err
 	}
 }
 
+func (s *CpuSubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	if res.CpuShare != "" {
+		shares, err := strconv.ParseUint(res.CpuShare, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid cpu share %s: %v", res.CpuShare, err)
+		}
+		weight := cpuSharesToWeight(shares)
+		if err := writeInt(path.Join(cgroupDir, "cpu.weight"), int64(weight)); err != nil {
+			return fmt.Errorf("set cgroup cpu weight fail %v", err)
+		}
+	}
+	// cpu.max 的格式为 "$QUOTA $PERIOD"，不限制时 quota 为 max
+	if res.CpuQuota != 0 || res.CpuPeriod != 0 {
+		quota := "max"
+		if res.CpuQuota > 0 {
+			quota = strconv.FormatInt(res.CpuQuota, 10)
+		}
+		period := res.CpuPeriod
+		if period == 0 {
+			period = DefaultCpuPeriod
+		}
+		cpuMax := fmt.Sprintf("%s %d", quota, period)
+		if err := writeVerified(path.Join(cgroupDir, "cpu.max"), cpuMax, sameValue(cpuMax)); err != nil {
+			return fmt.Errorf("set cgroup cpu max fail %v", err)
+		}
+	}
+	return nil
+}
+
+// CFS 默认的调度周期，单位 us
+const DefaultCpuPeriod = 100000
+
+// CpusToQuota converts the number of CPUs to the CFS quota in the period, the default
+// period is used if period is 0
+func CpusToQuota(cpus float64, period uint64) (int64, uint64) {
+	if period == 0 {
+		period = DefaultCpuPeriod
+	}
+	return int64(cpus * float64(period)), period
+}
+
+// cpuSharesToWeight converts cpu.shares [2, 262144] of v1 to cpu.weight [1, 10000] of v2
+func cpuSharesToWeight(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	} else if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142
+}
+
 func (s *CpuSubsystem) Remove(cgroupPath string) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
 		return os.Remove(subsysCgroupPath)
@@ -41,8 +102,8 @@ func (s *CpuSubsystem) Remove(cgroupPath string) error {
 func (s *CpuSubsystem) Apply(cgroupPath string, pid int) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
 		logrus.Info(subsysCgroupPath)
-		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
-			return fmt.Errorf("set cgroup proc fail %v", err)
+		if err := ApplyProcess(subsysCgroupPath, pid, "tasks"); err != nil {
+			return err
 		}
 		return nil
 	} else {
@@ -0,0 +1,27 @@
+package subsystems
+
+import "testing"
+
+func TestCpuSharesToWeight(t *testing.T) {
+	cases := map[uint64]uint64{
+		0:      1,
+		2:      1,
+		1024:   39,
+		262144: 10000,
+		300000: 10000,
+	}
+	for shares, weight := range cases {
+		if got := cpuSharesToWeight(shares); got != weight {
+			t.Errorf("cpuSharesToWeight(%d) = %d, want %d", shares, got, weight)
+		}
+	}
+}
+
+func TestCpusToQuota(t *testing.T) {
+	if quota, period := CpusToQuota(1.5, 0); quota != 150000 || period != DefaultCpuPeriod {
+		t.Errorf("CpusToQuota(1.5, 0) = %d, %d", quota, period)
+	}
+	if quota, period := CpusToQuota(0.5, 50000); quota != 25000 || period != 50000 {
+		t.Errorf("CpusToQuota(0.5, 50000) = %d, %d", quota, period)
+	}
+}
@@ -0,0 +1,43 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+)
+
+// CpuacctSubsystem 没有资源限制，只用于统计cgroup中进程的CPU使用时间
+type CpuacctSubsystem struct {
+}
+
+func (s *CpuacctSubsystem) Name() string {
+	return "cpuacct"
+}
+
+func (s *CpuacctSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	_, err := GetCgroupPath(s.Name(), cgroupPath, true)
+	return err
+}
+
+// v2 中由 cpu.stat 统计CPU使用时间
+func (s *CpuacctSubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	return nil
+}
+
+func (s *CpuacctSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		return os.Remove(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+func (s *CpuacctSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ApplyProcess(subsysCgroupPath, pid, "tasks"); err != nil {
+			return err
+		}
+		return nil
+	} else {
+		return fmt.Errorf("get cgroup %s error: %v", cgroupPath, err)
+	}
+}
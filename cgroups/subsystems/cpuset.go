@@ -5,7 +5,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type CpusetSubsystem struct {
@@ -16,10 +18,31 @@ func (s *CpusetSubsystem) Name() string {
 }
 
 func (s *CpusetSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
-	if subsycCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
-		if res.CpuShare != "" {
-			if err := ioutil.WriteFile(path.Join(subsycCgroupPath, "cpu.cpus"), []byte(res.CpuSet), 0644); err != nil {
-				return fmt.Errorf("set cgroup cpuset fail %v", err)
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
+		// 新建的 cpuset cgroup 中 cpus 和 mems 为空，此时无法加入进程，未指定时使用父 cgroup 的配置
+		files := []struct {
+			file      string
+			effective string
+			value     string
+		}{
+			{"cpuset.cpus", "cpuset.effective_cpus", res.CpuSet},
+			{"cpuset.mems", "cpuset.effective_mems", res.CpusetMems},
+		}
+		for _, f := range files {
+			parent := path.Dir(subsysCgroupPath)
+			if err := initCpuset(parent, f.file); err != nil {
+				return err
+			}
+			value := f.value
+			if value == "" {
+				if value, err = readCpusetFile(parent, f.file); err != nil {
+					return err
+				}
+			} else if err := checkCpusetAvailable(parent, f.effective, value); err != nil {
+				return err
+			}
+			if err := writeVerified(path.Join(subsysCgroupPath, f.file), value, sameCpuList(value)); err != nil {
+				return fmt.Errorf("set cgroup %s fail %v", f.file, err)
 			}
 		}
 		return nil
@@ -28,6 +51,95 @@ func (s *CpusetSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	}
 }
 
+// initCpuset copies the cpuset file from the parent if it is empty, ancestors created
+// by mydocker are initialized first
+func initCpuset(cgroupPath string, file string) error {
+	value, err := readCpusetFile(cgroupPath, file)
+	if err != nil || value != "" {
+		return err
+	}
+	parent := path.Dir(cgroupPath)
+	if err := initCpuset(parent, file); err != nil {
+		return err
+	}
+	if value, err = readCpusetFile(parent, file); err != nil {
+		return err
+	}
+	if err := writeVerified(path.Join(cgroupPath, file), value, sameCpuList(value)); err != nil {
+		return fmt.Errorf("init cgroup %s fail %v", file, err)
+	}
+	return nil
+}
+
+func readCpusetFile(cgroupPath string, file string) (string, error) {
+	content, err := ioutil.ReadFile(path.Join(cgroupPath, file))
+	if err != nil {
+		return "", fmt.Errorf("read %s fail %v", file, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// checkCpusetAvailable checks the requested cpus or memory nodes are a subset of the
+// effective ones of the parent cgroup
+func checkCpusetAvailable(parent string, effectiveFile string, requested string) error {
+	effective, err := readCpusetFile(parent, effectiveFile)
+	if err != nil {
+		return err
+	}
+	available, err := ParseCpuList(effective)
+	if err != nil {
+		return fmt.Errorf("parse %s fail %v", effectiveFile, err)
+	}
+	requestedList, err := ParseCpuList(requested)
+	if err != nil {
+		return err
+	}
+	availableSet := make(map[int]bool)
+	for _, i := range available {
+		availableSet[i] = true
+	}
+	var missing []string
+	for _, i := range requestedList {
+		if !availableSet[i] {
+			missing = append(missing, strconv.Itoa(i))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s are not available, %s of the parent cgroup is %q", strings.Join(missing, ","), effectiveFile, effective)
+	}
+	return nil
+}
+
+func (s *CpusetSubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	// v2 中 cpuset.cpus 和 cpuset.mems 为空时使用父 cgroup 的配置，不需要初始化
+	files := []struct {
+		file      string
+		effective string
+		value     string
+	}{
+		{"cpuset.cpus", "cpuset.cpus.effective", res.CpuSet},
+		{"cpuset.mems", "cpuset.mems.effective", res.CpusetMems},
+	}
+	for _, f := range files {
+		if f.value == "" {
+			// 之前设置过时恢复为继承父 cgroup，例如独占的 CPU 被释放后
+			if current, err := readCpusetFile(cgroupDir, f.file); err == nil && current != "" {
+				if err := writeVerified(path.Join(cgroupDir, f.file), "", sameValue("")); err != nil {
+					return fmt.Errorf("reset cgroup %s fail %v", f.file, err)
+				}
+			}
+			continue
+		}
+		if err := checkCpusetAvailable(path.Dir(cgroupDir), f.effective, f.value); err != nil {
+			return err
+		}
+		if err := writeVerified(path.Join(cgroupDir, f.file), f.value, sameCpuList(f.value)); err != nil {
+			return fmt.Errorf("set cgroup %s fail %v", f.file, err)
+		}
+	}
+	return nil
+}
+
 func (s *CpusetSubsystem) Remove(cgroupPath string) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
 		return os.Remove(subsysCgroupPath)
@@ -38,11 +150,82 @@ func (s *CpusetSubsystem) Remove(cgroupPath string) error {
 
 func (s *CpusetSubsystem) Apply(cgroupPath string, pid int) error {
 	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
-		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err != nil {
-			return fmt.Errorf("set cgroup proc fail %v", err)
+		if err := ApplyProcess(subsysCgroupPath, pid, "tasks"); err != nil {
+			return err
 		}
 		return nil
 	} else {
 		return fmt.Errorf("get cgroup %s error: %v", cgroupPath, err)
 	}
 }
+
+// EffectiveCpus returns the cpus which can be used by containers, that is the effective
+// cpus of the root cpuset cgroup or the online cpus if cpuset is not available
+func EffectiveCpus() ([]int, error) {
+	var cpusFile string
+	if IsCgroup2UnifiedMode() {
+		cpusFile = path.Join(cgroupRootDir, "cpuset.cpus.effective")
+	} else if mountPoint := FindCgroupMountPoint("cpuset"); mountPoint != "" {
+		cpusFile = path.Join(mountPoint, "cpuset.effective_cpus")
+	}
+	content, err := ioutil.ReadFile(cpusFile)
+	if err != nil {
+		if content, err = ioutil.ReadFile("/sys/devices/system/cpu/online"); err != nil {
+			return nil, fmt.Errorf("read online cpus fail %v", err)
+		}
+	}
+	return ParseCpuList(strings.TrimSpace(string(content)))
+}
+
+// FormatCpuList formats the cpus as a list such as "0-3,5"
+func FormatCpuList(cpus []int) string {
+	sorted := append([]int{}, cpus...)
+	sort.Ints(sorted)
+	var items []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			items = append(items, strconv.Itoa(sorted[i]))
+		} else {
+			items = append(items, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(items, ",")
+}
+
+// 内核支持的最大 CPU 数，避免解析过大的范围
+const maxCpuNum = 8192
+
+// ParseCpuList parses a list of cpus or memory nodes such as "0-3,5" and returns the
+// sorted numbers without duplicates
+func ParseCpuList(list string) ([]int, error) {
+	set := make(map[int]bool)
+	if list != "" {
+		for _, item := range strings.Split(list, ",") {
+			bounds := strings.SplitN(item, "-", 2)
+			start, err := strconv.Atoi(bounds[0])
+			if err != nil || start < 0 || start >= maxCpuNum {
+				return nil, fmt.Errorf("invalid cpu list %q", list)
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil || end < start || end >= maxCpuNum {
+					return nil, fmt.Errorf("invalid cpu list %q", list)
+				}
+			}
+			for i := start; i <= end; i++ {
+				set[i] = true
+			}
+		}
+	}
+	cpus := make([]int, 0, len(set))
+	for cpu := range set {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
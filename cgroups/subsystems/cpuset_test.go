@@ -0,0 +1,72 @@
+package subsystems
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckCpusetAvailable(t *testing.T) {
+	parent := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(parent, "cpuset.effective_cpus"), []byte("0-3,6\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{
+		"0":     true,
+		"1-3":   true,
+		"0,2,6": true,
+		"0-3,6": true,
+		"4":     false,
+		"2-5":   false,
+		"6-7":   false,
+		"x":     false,
+	}
+	for requested, available := range cases {
+		err := checkCpusetAvailable(parent, "cpuset.effective_cpus", requested)
+		if available && err != nil {
+			t.Errorf("expect %s to be available: %v", requested, err)
+		} else if !available && err == nil {
+			t.Errorf("expect %s not to be available", requested)
+		}
+	}
+	if err := checkCpusetAvailable(parent, "cpuset.effective_mems", "0"); err == nil {
+		t.Error("expect error when the effective file is missing")
+	}
+}
+
+func TestInitCpuset(t *testing.T) {
+	root := t.TempDir()
+	// 新建 cgroup 的 cpuset.cpus 为空，需要从最近的非空祖先复制
+	files := map[string]string{"": "0-3\n", "a": "\n", "a/b": "\n"}
+	for dir, content := range files {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(root, dir, "cpuset.cpus"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := initCpuset(filepath.Join(root, "a/b"), "cpuset.cpus"); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"a", "a/b"} {
+		if value, err := readCpusetFile(filepath.Join(root, dir), "cpuset.cpus"); err != nil || value != "0-3" {
+			t.Errorf("cpuset.cpus of %s is %q, err %v", dir, value, err)
+		}
+	}
+
+	// 已经设置的 cpuset 不被覆盖
+	if err := ioutil.WriteFile(filepath.Join(root, "a/b", "cpuset.cpus"), []byte("1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := initCpuset(filepath.Join(root, "a/b"), "cpuset.cpus"); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := readCpusetFile(filepath.Join(root, "a/b"), "cpuset.cpus"); value != "1" {
+		t.Errorf("cpuset.cpus is overwritten to %q", value)
+	}
+	if err := initCpuset(filepath.Join(root, "a/b"), "cpuset.mems"); err == nil {
+		t.Error("expect error when the file is missing")
+	}
+}
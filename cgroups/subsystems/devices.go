@@ -0,0 +1,164 @@
+package subsystems
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// DeviceRule 允许cgroup中的进程以指定权限访问一类设备
+type DeviceRule struct {
+	Type        string `json:"type"`        // a 表示所有设备，b 为块设备，c 为字符设备
+	Major       int64  `json:"major"`       // -1 表示任意主设备号
+	Minor       int64  `json:"minor"`       // -1 表示任意次设备号
+	Permissions string `json:"permissions"` // r 读，w 写，m mknod 的组合
+}
+
+// String returns the rule in the format of devices.allow, e.g. "c 1:3 rwm"
+func (r DeviceRule) String() string {
+	return fmt.Sprintf("%s %s:%s %s", r.Type, deviceNumber(r.Major), deviceNumber(r.Minor), r.Permissions)
+}
+
+func deviceNumber(num int64) string {
+	if num == -1 {
+		return "*"
+	}
+	return strconv.FormatInt(num, 10)
+}
+
+// DefaultDeviceRules 是所有容器都允许访问的设备
+var DefaultDeviceRules = []DeviceRule{
+	// 允许 mknod 任意设备，但不能读写
+	{Type: "c", Major: -1, Minor: -1, Permissions: "m"},
+	{Type: "b", Major: -1, Minor: -1, Permissions: "m"},
+	{Type: "c", Major: 1, Minor: 3, Permissions: "rwm"},    // /dev/null
+	{Type: "c", Major: 1, Minor: 5, Permissions: "rwm"},    // /dev/zero
+	{Type: "c", Major: 1, Minor: 7, Permissions: "rwm"},    // /dev/full
+	{Type: "c", Major: 1, Minor: 8, Permissions: "rwm"},    // /dev/random
+	{Type: "c", Major: 1, Minor: 9, Permissions: "rwm"},    // /dev/urandom
+	{Type: "c", Major: 5, Minor: 0, Permissions: "rwm"},    // /dev/tty
+	{Type: "c", Major: 5, Minor: 1, Permissions: "rwm"},    // /dev/console
+	{Type: "c", Major: 5, Minor: 2, Permissions: "rwm"},    // /dev/ptmx
+	{Type: "c", Major: 136, Minor: -1, Permissions: "rwm"}, // /dev/pts/*
+	{Type: "c", Major: 10, Minor: 200, Permissions: "rwm"}, // /dev/net/tun
+}
+
+// DevicesSubsystem 只允许cgroup中的进程访问白名单中的设备，v1 中为 devices controller，
+// v2 中为挂载到 cgroup 上的 eBPF 程序
+type DevicesSubsystem struct {
+}
+
+func (s *DevicesSubsystem) Name() string {
+	return "devices"
+}
+
+func (s *DevicesSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
+		rules := mergeDeviceRules(deviceRules(res))
+		listFile := path.Join(subsysCgroupPath, "devices.list")
+		// 已经设置过时跳过，否则 update 时运行中的容器会短暂无法访问所有设备
+		if verifyDevicesList(listFile, rules) == nil {
+			return nil
+		}
+		// 先禁止所有设备，再逐条写入白名单
+		if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "devices.deny"), []byte("a"), 0644); err != nil {
+			return fmt.Errorf("set cgroup devices deny fail %v", err)
+		}
+		for _, rule := range rules {
+			if err := ioutil.WriteFile(path.Join(subsysCgroupPath, "devices.allow"), []byte(rule.String()), 0644); err != nil {
+				return fmt.Errorf("set cgroup devices allow %s fail %v", rule, err)
+			}
+		}
+		if err := verifyDevicesList(listFile, rules); err != nil {
+			return fmt.Errorf("set cgroup devices fail %v", err)
+		}
+		return nil
+	} else {
+		return err
+	}
+}
+
+// verifyDevicesList checks devices.list holds exactly the rules
+func verifyDevicesList(listFile string, rules []DeviceRule) error {
+	content, err := ioutil.ReadFile(listFile)
+	if err != nil {
+		return fmt.Errorf("read back devices.list fail %v", err)
+	}
+	actual := strings.TrimSpace(string(content))
+	for _, rule := range rules {
+		if !containsLine(rule.String())(actual) {
+			return fmt.Errorf("kernel did not accept %q, devices.list is %q", rule, actual)
+		}
+	}
+	if lines := strings.Split(actual, "\n"); len(lines) != len(rules) {
+		return fmt.Errorf("devices.list has %d rules, expect %d", len(lines), len(rules))
+	}
+	return nil
+}
+
+// mergeDeviceRules merges rules of the same devices like the kernel does for devices.list,
+// permissions are put in rwm order as the kernel lists them
+func mergeDeviceRules(rules []DeviceRule) []DeviceRule {
+	merged := make([]DeviceRule, 0, len(rules))
+	index := make(map[string]int)
+	for _, rule := range rules {
+		// 写入 a 时内核允许访问所有设备，devices.list 中只有 "a *:* rwm"
+		if rule.Type == "a" {
+			return []DeviceRule{{Type: "a", Major: -1, Minor: -1, Permissions: "rwm"}}
+		}
+		key := fmt.Sprintf("%s %d:%d", rule.Type, rule.Major, rule.Minor)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			rule.Permissions = normalizePermissions(rule.Permissions)
+			merged = append(merged, rule)
+			continue
+		}
+		merged[i].Permissions = normalizePermissions(merged[i].Permissions + rule.Permissions)
+	}
+	return merged
+}
+
+// normalizePermissions removes duplicated permissions and sorts them in rwm order
+func normalizePermissions(permissions string) string {
+	normalized := ""
+	for _, perm := range "rwm" {
+		if strings.ContainsRune(permissions, perm) {
+			normalized += string(perm)
+		}
+	}
+	return normalized
+}
+
+func (s *DevicesSubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	return attachDeviceFilter(cgroupDir, deviceRules(res))
+}
+
+func (s *DevicesSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		return os.Remove(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+func (s *DevicesSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ApplyProcess(subsysCgroupPath, pid, "tasks"); err != nil {
+			return err
+		}
+		return nil
+	} else {
+		return fmt.Errorf("get cgroup %s error: %v", cgroupPath, err)
+	}
+}
+
+// deviceRules returns the default rules followed by devices added to the container
+func deviceRules(res *ResourceConfig) []DeviceRule {
+	rules := make([]DeviceRule, 0, len(DefaultDeviceRules)+len(res.Devices))
+	rules = append(rules, DefaultDeviceRules...)
+	return append(rules, res.Devices...)
+}
@@ -0,0 +1,197 @@
+package subsystems
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// bpfInsn 对应内核中的 struct bpf_insn
+type bpfInsn struct {
+	Code uint8
+	Regs uint8 // 低 4 位为 dst 寄存器，高 4 位为 src 寄存器
+	Off  int16
+	Imm  int32
+}
+
+// bpf_attr 中 BPF_PROG_LOAD 使用的部分
+type bpfProgLoadAttr struct {
+	ProgType    uint32
+	InsnCnt     uint32
+	Insns       uint64
+	License     uint64
+	LogLevel    uint32
+	LogSize     uint32
+	LogBuf      uint64
+	KernVersion uint32
+}
+
+// bpf_attr 中 BPF_PROG_ATTACH 使用的部分
+type bpfProgAttachAttr struct {
+	TargetFd    uint32
+	AttachBpfFd uint32
+	AttachType  uint32
+	AttachFlags uint32
+}
+
+const (
+	regR0 = iota
+	regR1
+	regR2
+	regR3
+	regR4
+	regR5
+)
+
+// 跳转到下一条规则，生成规则后再计算偏移
+const jumpToNextRule = -1
+
+func ldxW(dst, src uint8, off int16) bpfInsn {
+	return bpfInsn{Code: unix.BPF_LDX | unix.BPF_MEM | unix.BPF_W, Regs: src<<4 | dst, Off: off}
+}
+
+func alu64K(op uint8, dst uint8, imm int32) bpfInsn {
+	return bpfInsn{Code: unix.BPF_ALU64 | op | unix.BPF_K, Regs: dst, Imm: imm}
+}
+
+func mov64X(dst, src uint8) bpfInsn {
+	return bpfInsn{Code: unix.BPF_ALU64 | unix.BPF_MOV | unix.BPF_X, Regs: src<<4 | dst}
+}
+
+func jneK(dst uint8, imm int32, off int16) bpfInsn {
+	return bpfInsn{Code: unix.BPF_JMP | unix.BPF_JNE | unix.BPF_K, Regs: dst, Off: off, Imm: imm}
+}
+
+func exit() bpfInsn {
+	return bpfInsn{Code: unix.BPF_JMP | unix.BPF_EXIT}
+}
+
+// deviceFilterProgram generates a BPF_PROG_TYPE_CGROUP_DEVICE program which returns 1
+// if the access matches any rule, the context is struct bpf_cgroup_dev_ctx
+func deviceFilterProgram(rules []DeviceRule) ([]bpfInsn, error) {
+	insns := []bpfInsn{
+		// r2 = 设备类型, r3 = 访问类型, r4 = major, r5 = minor
+		ldxW(regR2, regR1, 0),
+		alu64K(unix.BPF_AND, regR2, 0xffff),
+		ldxW(regR3, regR1, 0),
+		alu64K(unix.BPF_RSH, regR3, 16),
+		ldxW(regR4, regR1, 4),
+		ldxW(regR5, regR1, 8),
+	}
+	for _, rule := range rules {
+		var block []bpfInsn
+		switch rule.Type {
+		case "a":
+		case "b":
+			block = append(block, jneK(regR2, unix.BPF_DEVCG_DEV_BLOCK, jumpToNextRule))
+		case "c":
+			block = append(block, jneK(regR2, unix.BPF_DEVCG_DEV_CHAR, jumpToNextRule))
+		default:
+			return nil, fmt.Errorf("invalid device type %q", rule.Type)
+		}
+		var access int32
+		for _, perm := range rule.Permissions {
+			switch perm {
+			case 'r':
+				access |= unix.BPF_DEVCG_ACC_READ
+			case 'w':
+				access |= unix.BPF_DEVCG_ACC_WRITE
+			case 'm':
+				access |= unix.BPF_DEVCG_ACC_MKNOD
+			default:
+				return nil, fmt.Errorf("invalid device permissions %q", rule.Permissions)
+			}
+		}
+		allAccess := int32(unix.BPF_DEVCG_ACC_READ | unix.BPF_DEVCG_ACC_WRITE | unix.BPF_DEVCG_ACC_MKNOD)
+		if access != allAccess {
+			// 请求的访问中有规则不允许的权限时不匹配
+			block = append(block,
+				mov64X(regR1, regR3),
+				alu64K(unix.BPF_AND, regR1, allAccess&^access),
+				jneK(regR1, 0, jumpToNextRule),
+			)
+		}
+		if rule.Major != -1 {
+			block = append(block, jneK(regR4, int32(rule.Major), jumpToNextRule))
+		}
+		if rule.Minor != -1 {
+			block = append(block, jneK(regR5, int32(rule.Minor), jumpToNextRule))
+		}
+		block = append(block, alu64K(unix.BPF_MOV, regR0, 1), exit())
+		for i := range block {
+			if block[i].Off == jumpToNextRule && block[i].Code == unix.BPF_JMP|unix.BPF_JNE|unix.BPF_K {
+				block[i].Off = int16(len(block) - i - 1)
+			}
+		}
+		insns = append(insns, block...)
+	}
+	// 没有匹配的规则时拒绝访问
+	return append(insns, alu64K(unix.BPF_MOV, regR0, 0), exit()), nil
+}
+
+// attachDeviceFilter loads the device filter program of rules and attaches it to the
+// cgroup, a program attached before is replaced
+func attachDeviceFilter(cgroupDir string, rules []DeviceRule) error {
+	insns, err := deviceFilterProgram(rules)
+	if err != nil {
+		return err
+	}
+	progFd, err := loadDeviceFilter(insns, false)
+	if err != nil {
+		// 加载失败时带上 verifier 的日志重新加载，用于输出错误原因
+		if _, logErr := loadDeviceFilter(insns, true); logErr != nil {
+			err = logErr
+		}
+		return fmt.Errorf("load device filter program fail %v", err)
+	}
+	// cgroup 持有程序的引用，挂载后即可关闭
+	defer unix.Close(progFd)
+
+	cgroupFd, err := unix.Open(cgroupDir, unix.O_DIRECTORY|unix.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open cgroup %s fail %v", cgroupDir, err)
+	}
+	defer unix.Close(cgroupFd)
+	attachAttr := bpfProgAttachAttr{
+		TargetFd:    uint32(cgroupFd),
+		AttachBpfFd: uint32(progFd),
+		AttachType:  unix.BPF_CGROUP_DEVICE,
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_ATTACH, uintptr(unsafe.Pointer(&attachAttr)), unsafe.Sizeof(attachAttr)); errno != 0 {
+		return fmt.Errorf("attach device filter program fail %v", errno)
+	}
+	return nil
+}
+
+// loadDeviceFilter loads the program and returns its fd, the verifier log is returned in
+// the error if withLog is set
+func loadDeviceFilter(insns []bpfInsn, withLog bool) (int, error) {
+	license := []byte("GPL\x00")
+	attr := bpfProgLoadAttr{
+		ProgType: unix.BPF_PROG_TYPE_CGROUP_DEVICE,
+		InsnCnt:  uint32(len(insns)),
+		Insns:    uint64(uintptr(unsafe.Pointer(&insns[0]))),
+		License:  uint64(uintptr(unsafe.Pointer(&license[0]))),
+	}
+	var logBuf []byte
+	if withLog {
+		logBuf = make([]byte, 1<<20)
+		attr.LogLevel = 1
+		attr.LogSize = uint32(len(logBuf))
+		attr.LogBuf = uint64(uintptr(unsafe.Pointer(&logBuf[0])))
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_BPF, unix.BPF_PROG_LOAD, uintptr(unsafe.Pointer(&attr)), unsafe.Sizeof(attr))
+	runtime.KeepAlive(insns)
+	runtime.KeepAlive(license)
+	runtime.KeepAlive(logBuf)
+	if errno != 0 {
+		if withLog {
+			return -1, fmt.Errorf("%v: %s", errno, strings.TrimRight(string(logBuf), "\x00"))
+		}
+		return -1, errno
+	}
+	return int(fd), nil
+}
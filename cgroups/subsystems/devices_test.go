@@ -0,0 +1,46 @@
+package subsystems
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeDeviceRules(t *testing.T) {
+	rules := mergeDeviceRules([]DeviceRule{
+		{Type: "b", Major: 8, Minor: 0, Permissions: "wr"},
+		{Type: "c", Major: 1, Minor: 3, Permissions: "m"},
+		{Type: "c", Major: 1, Minor: 3, Permissions: "wr"},
+	})
+	expected := []DeviceRule{
+		{Type: "b", Major: 8, Minor: 0, Permissions: "rw"},
+		{Type: "c", Major: 1, Minor: 3, Permissions: "rwm"},
+	}
+	if !reflect.DeepEqual(rules, expected) {
+		t.Errorf("unexpected rules %v", rules)
+	}
+
+	rules = mergeDeviceRules(append(append([]DeviceRule{}, DefaultDeviceRules...), DeviceRule{Type: "a", Major: -1, Minor: -1, Permissions: "r"}))
+	if len(rules) != 1 || rules[0].String() != "a *:* rwm" {
+		t.Errorf("expect a single allow-all rule, got %v", rules)
+	}
+}
+
+func TestVerifyDevicesList(t *testing.T) {
+	listFile := filepath.Join(t.TempDir(), "devices.list")
+	// 内核按 rwm 的顺序列出权限
+	if err := ioutil.WriteFile(listFile, []byte("c 1:3 rwm\nb 8:0 rw\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules := mergeDeviceRules([]DeviceRule{
+		{Type: "c", Major: 1, Minor: 3, Permissions: "rwm"},
+		{Type: "b", Major: 8, Minor: 0, Permissions: "wr"},
+	})
+	if err := verifyDevicesList(listFile, rules); err != nil {
+		t.Error(err)
+	}
+	if err := verifyDevicesList(listFile, rules[:1]); err == nil {
+		t.Error("expect error for an extra rule in devices.list")
+	}
+}
@@ -0,0 +1,124 @@
+package subsystems
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// 等待 cgroup 中的进程全部冻结的最长时间
+const freezeTimeout = 10 * time.Second
+
+// FreezerSubsystem freezes and thaws processes of a cgroup, it uses the v1 freezer
+// controller if mounted, otherwise the cgroup.freeze file of cgroup v2
+type FreezerSubsystem struct {
+}
+
+func (s *FreezerSubsystem) Name() string {
+	return "freezer"
+}
+
+func (s *FreezerSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	_, err := s.getCgroupPath(cgroupPath, true)
+	return err
+}
+
+// 冻结通过 cgroup.freeze 完成，v2 中没有需要设置的限制
+func (s *FreezerSubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	return nil
+}
+
+func (s *FreezerSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := s.getCgroupPath(cgroupPath, false); err == nil {
+		return os.Remove(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+func (s *FreezerSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := s.getCgroupPath(cgroupPath, false); err == nil {
+		tasksFile := "tasks"
+		if s.isUnified() {
+			tasksFile = "cgroup.threads"
+		}
+		if err := ApplyProcess(subsysCgroupPath, pid, tasksFile); err != nil {
+			return err
+		}
+		return nil
+	} else {
+		return fmt.Errorf("get cgroup %s error: %v", cgroupPath, err)
+	}
+}
+
+// Freeze stops all processes in the cgroup and waits until they are frozen
+func (s *FreezerSubsystem) Freeze(cgroupPath string) error {
+	return s.setFrozen(cgroupPath, true)
+}
+
+// Thaw resumes all processes in the cgroup
+func (s *FreezerSubsystem) Thaw(cgroupPath string) error {
+	return s.setFrozen(cgroupPath, false)
+}
+
+func (s *FreezerSubsystem) setFrozen(cgroupPath string, frozen bool) error {
+	subsysCgroupPath, err := s.getCgroupPath(cgroupPath, false)
+	if err != nil {
+		return fmt.Errorf("get cgroup %s error: %v", cgroupPath, err)
+	}
+	// v1: freezer.state 写入 FROZEN/THAWED，冻结过程中读到 FREEZING
+	// v2: cgroup.freeze 写入 1/0，cgroup.events 中的 frozen 表示是否完成
+	stateFile, state, expected := "freezer.state", "THAWED", "THAWED"
+	if frozen {
+		state, expected = "FROZEN", "FROZEN"
+	}
+	if s.isUnified() {
+		stateFile, state, expected = "cgroup.freeze", "0", "frozen 0"
+		if frozen {
+			state, expected = "1", "frozen 1"
+		}
+	}
+	if err := ioutil.WriteFile(path.Join(subsysCgroupPath, stateFile), []byte(state), 0644); err != nil {
+		return fmt.Errorf("set cgroup freezer state fail %v", err)
+	}
+
+	checkFile := stateFile
+	if s.isUnified() {
+		checkFile = "cgroup.events"
+	}
+	deadline := time.Now().Add(freezeTimeout)
+	for {
+		content, err := ioutil.ReadFile(path.Join(subsysCgroupPath, checkFile))
+		if err != nil {
+			return fmt.Errorf("read cgroup freezer state fail %v", err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.TrimSpace(line) == expected {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for cgroup %s to be %s", cgroupPath, expected)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// isUnified reports whether the cgroup v2 freeze file is used instead of the v1 freezer
+func (s *FreezerSubsystem) isUnified() bool {
+	return IsCgroup2UnifiedMode() || FindCgroupMountPoint(s.Name()) == ""
+}
+
+func (s *FreezerSubsystem) getCgroupPath(cgroupPath string, autoCreate bool) (string, error) {
+	if !s.isUnified() {
+		return GetCgroupPath(s.Name(), cgroupPath, autoCreate)
+	}
+	cgroupRoot := FindCgroup2MountPoint()
+	if cgroupRoot == "" {
+		return "", fmt.Errorf("neither freezer nor cgroup2 is mounted")
+	}
+	return getCgroupPathInRoot(cgroupRoot, cgroupPath, autoCreate)
+}
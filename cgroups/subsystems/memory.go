@@ -6,7 +6,7 @@ import (
 	"os"
 	"path"
 	"strconv"
-
+	"strings"
 )
 
 type MemorySubsystem struct {
@@ -18,9 +18,22 @@ func (s *MemorySubsystem) Name() string {
 
 func (s *MemorySubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	if subSystemCgruopPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
-		if res.MemoryLimit != "" {
-			if err := ioutil.WriteFile(path.Join(subSystemCgruopPath, "memory.limit_in_bytes"), []byte(res.MemoryLimit), 0644); err != nil {
-				return fmt.Errorf("set cgroup  memory fail %v", err)
+		if err := setMemoryAndSwap(subSystemCgruopPath, res); err != nil {
+			return err
+		}
+		if res.MemoryReservation != 0 {
+			if err := writeMemory(path.Join(subSystemCgruopPath, "memory.soft_limit_in_bytes"), res.MemoryReservation); err != nil {
+				return fmt.Errorf("set cgroup memory reservation fail %v", err)
+			}
+		}
+		if res.MemorySwappiness != nil {
+			if err := writeInt(path.Join(subSystemCgruopPath, "memory.swappiness"), *res.MemorySwappiness); err != nil {
+				return fmt.Errorf("set cgroup memory swappiness fail %v", err)
+			}
+		}
+		if res.OomKillDisable {
+			if err := writeVerified(path.Join(subSystemCgruopPath, "memory.oom_control"), "1", hasKeyValue("oom_kill_disable", "1")); err != nil {
+				return fmt.Errorf("disable cgroup oom killer fail %v", err)
 			}
 		}
 		return nil
@@ -29,6 +42,78 @@ func (s *MemorySubsystem) Set(cgroupPath string, res *ResourceConfig) error {
 	}
 }
 
+// setMemoryAndSwap sets memory.limit_in_bytes and memory.memsw.limit_in_bytes, the memory
+// limit can never be greater than the memory+swap limit so the order of writes matters
+func setMemoryAndSwap(cgroupPath string, res *ResourceConfig) error {
+	limitFile := path.Join(cgroupPath, "memory.limit_in_bytes")
+	swapFile := path.Join(cgroupPath, "memory.memsw.limit_in_bytes")
+	if res.MemorySwap == 0 {
+		if res.MemoryLimit != 0 {
+			if err := writeMemory(limitFile, res.MemoryLimit); err != nil {
+				return fmt.Errorf("set cgroup  memory fail %v", err)
+			}
+		}
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(swapFile)
+	if err != nil {
+		return fmt.Errorf("memory swap limit is not supported by the kernel: %v", err)
+	}
+	currentSwap, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return fmt.Errorf("parse %s fail %v", swapFile, err)
+	}
+	// 提高 memory+swap 限制时先写 memsw，降低时先写 memory
+	swapFirst := res.MemorySwap == -1 || res.MemorySwap > currentSwap
+	if swapFirst {
+		if err := writeMemory(swapFile, res.MemorySwap); err != nil {
+			return fmt.Errorf("set cgroup memory swap fail %v", err)
+		}
+	}
+	if res.MemoryLimit != 0 {
+		if err := writeMemory(limitFile, res.MemoryLimit); err != nil {
+			return fmt.Errorf("set cgroup  memory fail %v", err)
+		}
+	}
+	if !swapFirst {
+		if err := writeMemory(swapFile, res.MemorySwap); err != nil {
+			return fmt.Errorf("set cgroup memory swap fail %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *MemorySubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	if res.MemoryLimit != 0 {
+		if err := writeMemory(path.Join(cgroupDir, "memory.max"), res.MemoryLimit); err != nil {
+			return fmt.Errorf("set cgroup  memory fail %v", err)
+		}
+	}
+	// v2 中 memory.swap.max 只限制 swap 的用量
+	if res.MemorySwap == -1 {
+		if err := writeVerified(path.Join(cgroupDir, "memory.swap.max"), "max", sameValue("max")); err != nil {
+			return fmt.Errorf("set cgroup memory swap fail %v", err)
+		}
+	} else if res.MemorySwap != 0 {
+		if err := writeMemory(path.Join(cgroupDir, "memory.swap.max"), res.MemorySwap-res.MemoryLimit); err != nil {
+			return fmt.Errorf("set cgroup memory swap fail %v", err)
+		}
+	}
+	if res.MemoryReservation != 0 {
+		if err := writeMemory(path.Join(cgroupDir, "memory.low"), res.MemoryReservation); err != nil {
+			return fmt.Errorf("set cgroup memory reservation fail %v", err)
+		}
+	}
+	if res.MemorySwappiness != nil {
+		return fmt.Errorf("memory swappiness is not supported by cgroup v2")
+	}
+	if res.OomKillDisable {
+		return fmt.Errorf("disabling the oom killer is not supported by cgroup v2")
+	}
+	return nil
+}
+
 func (s *MemorySubsystem) Remove(cgroupPath string) error {
 	if subSystemCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
 		return os.Remove(subSystemCgroupPath)
@@ -39,9 +124,9 @@ func (s *MemorySubsystem) Remove(cgroupPath string) error {
 
 
 func (s *MemorySubsystem) Apply(cgroupPath  string, pid int) error{
-	if subSystemCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err != nil{
-		if err := ioutil.WriteFile(path.Join(subSystemCgroupPath, "tasks"), []byte(strconv.Itoa(pid)), 0644); err !=  nil{
-			return fmt.Errorf("set cgroup proc fail %v", err)
+	if subSystemCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil{
+		if err := ApplyProcess(subSystemCgroupPath, pid, "tasks"); err !=  nil{
+			return err
 		}
 		return nil
 	}else{
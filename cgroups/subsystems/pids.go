@@ -0,0 +1,65 @@
+package subsystems
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+)
+
+// PidsSubsystem 限制cgroup中的进程数，防止容器中的fork炸弹耗尽host的pid
+type PidsSubsystem struct {
+}
+
+func (s *PidsSubsystem) Name() string {
+	return "pids"
+}
+
+func (s *PidsSubsystem) Set(cgroupPath string, res *ResourceConfig) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, true); err == nil {
+		if res.PidsLimit != 0 {
+			if err := writeVerified(path.Join(subsysCgroupPath, "pids.max"), pidsMax(res.PidsLimit), sameValue(pidsMax(res.PidsLimit))); err != nil {
+				return fmt.Errorf("set cgroup pids fail %v", err)
+			}
+		}
+		return nil
+	} else {
+		return err
+	}
+}
+
+func (s *PidsSubsystem) SetUnified(cgroupDir string, res *ResourceConfig) error {
+	if res.PidsLimit != 0 {
+		if err := writeVerified(path.Join(cgroupDir, "pids.max"), pidsMax(res.PidsLimit), sameValue(pidsMax(res.PidsLimit))); err != nil {
+			return fmt.Errorf("set cgroup pids fail %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *PidsSubsystem) Remove(cgroupPath string) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		return os.Remove(subsysCgroupPath)
+	} else {
+		return err
+	}
+}
+
+func (s *PidsSubsystem) Apply(cgroupPath string, pid int) error {
+	if subsysCgroupPath, err := GetCgroupPath(s.Name(), cgroupPath, false); err == nil {
+		if err := ApplyProcess(subsysCgroupPath, pid, "tasks"); err != nil {
+			return err
+		}
+		return nil
+	} else {
+		return fmt.Errorf("get cgroup %s error: %v", cgroupPath, err)
+	}
+}
+
+// pidsMax converts the limit to the content of pids.max, a negative limit means unlimited
+func pidsMax(limit int64) string {
+	if limit < 0 {
+		return "max"
+	}
+	return strconv.FormatInt(limit, 10)
+}
@@ -2,9 +2,25 @@ package subsystems
 
 
 type ResourceConfig struct{
-	MemoryLimit string
-	CpuShare string	// CPU timeslice weight
-	CpuSet string	// CPU cors
+	MemoryLimit int64	`json:"memory"`	// memory limit in bytes, 0 means not set
+	MemorySwap int64	`json:"memorySwap"`	// memory plus swap limit in bytes, 0 means not set and -1 means unlimited swap
+	MemoryReservation int64	`json:"memoryReservation"`	// memory soft limit in bytes
+	MemorySwappiness *int64	`json:"memorySwappiness,omitempty"`	// tendency to swap [0, 100], nil means not set
+	OomKillDisable bool	`json:"oomKillDisable"`	// disable the OOM killer of the cgroup
+	OomScoreAdj int	`json:"oomScoreAdj"`	// oom_score_adj of the container process
+	CpuShare string	`json:"cpushare"`	// CPU timeslice weight
+	CpuSet string	`json:"cpuset"`	// CPU cors
+	CpusetMems string	`json:"cpusetMems"`	// memory nodes, empty means all nodes of the parent
+	CpusExclusive int	`json:"cpusExclusive"`	// number of cpus allocated exclusively, CpuSet holds the allocated cpus
+	CpuPeriod uint64	`json:"cpuPeriod"`	// CFS period in microseconds, 0 means the kernel default
+	CpuQuota int64	`json:"cpuQuota"`	// CFS quota in microseconds, 0 means not set and -1 means unlimited
+	BlkioWeight uint16	`json:"blkioWeight"`	// relative block IO weight [10, 1000], 0 means not set
+	BlkioDeviceReadBps []ThrottleDevice	`json:"blkioDeviceReadBps"`	// read rate limit in bytes per second of devices
+	BlkioDeviceWriteBps []ThrottleDevice	`json:"blkioDeviceWriteBps"`	// write rate limit in bytes per second of devices
+	BlkioDeviceReadIOps []ThrottleDevice	`json:"blkioDeviceReadIOps"`	// read rate limit in IO per second of devices
+	BlkioDeviceWriteIOps []ThrottleDevice	`json:"blkioDeviceWriteIOps"`	// write rate limit in IO per second of devices
+	PidsLimit int64	`json:"pidsLimit"`	// max number of processes, 0 means not set and -1 means unlimited
+	Devices []DeviceRule	`json:"devices"`	// devices allowed besides DefaultDeviceRules
 }
 
 type Subsystem interface{
@@ -12,6 +28,7 @@ type Subsystem interface{
 	Set(path string,  res *ResourceConfig) error	// set the limitation of the cgroup on the specific subsystem
 	Apply(path string, pid int) error	// add the process to the specific cgroup(specified by path)	
 	Remove(path string) error // remove the cgroup
+	SetUnified(cgroupDir string, res *ResourceConfig) error	// set the limitation in the cgroup v2 directory(absolute path) shared by all controllers
 }
 
 var(
@@ -19,5 +36,16 @@ var(
 		&CpusetSubsystem{},
 		&MemorySubsystem{},
 		&CpuSubsystem{},
+		&CpuacctSubsystem{},
+		&FreezerSubsystem{},
+		&PidsSubsystem{},
+		&BlkioSubsystem{},
+		&DevicesSubsystem{},
+	}
+	// 设置 cgroup parent 的总体限制时使用的 subsystem，不限制其中容器的设备和 cpuset
+	ParentSubsystemIns = []Subsystem{
+		&MemorySubsystem{},
+		&CpuSubsystem{},
+		&PidsSubsystem{},
 	}
 )
\ No newline at end of file
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path"
 	"strings"
+
+	"golang.org/x/sys/unix"
 )
 
 func FindCgroupMountPoint(subsystem string) string{
@@ -31,11 +33,73 @@ func FindCgroupMountPoint(subsystem string) string{
 	return ""
 }
 
+// 所有controller都挂载在unified hierarchy时cgroup根目录的挂载点
+const cgroupRootDir = "/sys/fs/cgroup"
+
+// IsCgroup2UnifiedMode reports whether the cgroup root is the cgroup v2 unified hierarchy
+func IsCgroup2UnifiedMode() bool{
+	var st unix.Statfs_t
+	if err := unix.Statfs(cgroupRootDir, &st); err != nil{
+		return false
+	}
+	return st.Type == unix.CGROUP2_SUPER_MAGIC
+}
+
+// FindCgroup2MountPoint returns the mount point of the cgroup v2 unified hierarchy
+func FindCgroup2MountPoint() string{
+	if IsCgroup2UnifiedMode(){
+		return cgroupRootDir
+	}
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil{
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan(){
+		fields := strings.Split(scanner.Text(), " ")
+		// 文件系统类型位于分隔符 "-" 之后
+		for i, field := range fields{
+			if field == "-" && i + 1 < len(fields) && fields[i + 1] == "cgroup2"{
+				return fields[4]
+			}
+		}
+	}
+	return ""
+}
+
+// StatDevice returns the type('b' for block and 'c' for char) and the major:minor number
+// of the device file
+func StatDevice(devicePath string) (devType byte, major int64, minor int64, err error){
+	var st unix.Stat_t
+	if err := unix.Stat(devicePath, &st); err != nil{
+		return 0, 0, 0, fmt.Errorf("stat device %s failed: %v", devicePath, err)
+	}
+	switch st.Mode & unix.S_IFMT{
+	case unix.S_IFBLK:
+		devType = 'b'
+	case unix.S_IFCHR:
+		devType = 'c'
+	default:
+		return 0, 0, 0, fmt.Errorf("%s is not a device", devicePath)
+	}
+	return devType, int64(unix.Major(uint64(st.Rdev))), int64(unix.Minor(uint64(st.Rdev))), nil
+}
+
 func GetCgroupPath(subsystem string, cgroupPath string, autoCreate bool)(string, error){
 	cgroupRoot := FindCgroupMountPoint(subsystem)
+	if cgroupRoot == ""{
+		return "", fmt.Errorf("cgroup subsystem %s is not mounted", subsystem)
+	}
+	return getCgroupPathInRoot(cgroupRoot, cgroupPath, autoCreate)
+}
+
+func getCgroupPathInRoot(cgroupRoot string, cgroupPath string, autoCreate bool)(string, error){
 	if _, err := os.Stat(path.Join(cgroupRoot, cgroupPath)); err == nil || (autoCreate && os.IsNotExist(err)){
 		if os.IsNotExist(err){
-			if err := os.Mkdir(path.Join(cgroupRoot, cgroupPath), 0755); err != nil{
+			// 同时创建 cgroup parent 中不存在的中间 cgroup
+			if err := os.MkdirAll(path.Join(cgroupRoot, cgroupPath), 0755); err != nil{
 				return "", fmt.Errorf("error create cgroup %v", err)
 			}
 		}
@@ -44,6 +108,38 @@ func GetCgroupPath(subsystem string, cgroupPath string, autoCreate bool)(string,
 		return "", fmt.Errorf("cgroup path error %v", err)
 	}
 }
+// CgroupMount 是 /proc/self/mountinfo 中的一个 cgroup 文件系统
+type CgroupMount struct{
+	MountPoint string
+	Type       string // cgroup 或 cgroup2
+	Options    string // super options，v1 中为 hierarchy 的 controller，例如 cpu,cpuacct
+}
+
+// FindCgroupMounts returns all cgroup v1 and v2 mounts of the current mount namespace
+func FindCgroupMounts() ([]CgroupMount, error){
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil{
+		return nil, err
+	}
+	defer f.Close()
+
+	var mounts []CgroupMount
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan(){
+		fields := strings.Split(scanner.Text(), " ")
+		for i, field := range fields{
+			if field != "-" || i + 3 >= len(fields){
+				continue
+			}
+			if fields[i + 1] == "cgroup" || fields[i + 1] == "cgroup2"{
+				mounts = append(mounts, CgroupMount{MountPoint: fields[4], Type: fields[i + 1], Options: fields[i + 3]})
+			}
+			break
+		}
+	}
+	return mounts, scanner.Err()
+}
+
 /**  
 MountInfo example:
 	34 24 8:16 / / rw,relatime - ext4 /dev/sdb rw,discard,errors=remount-ro,data=ordered
@@ -0,0 +1,19 @@
+package subsystems
+
+import "testing"
+
+func TestStatDevice(t *testing.T) {
+	devType, major, minor, err := StatDevice("/dev/null")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devType != 'c' || major != 1 || minor != 3 {
+		t.Errorf("unexpected /dev/null device: %c %d:%d", devType, major, minor)
+	}
+	if _, _, _, err := StatDevice("/"); err == nil {
+		t.Error("expect error for a directory")
+	}
+	if _, err := NewThrottleDevice("/dev/null", 1024); err == nil {
+		t.Error("expect error for a char device")
+	}
+}
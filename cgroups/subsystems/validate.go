@@ -0,0 +1,91 @@
+package subsystems
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// cpu.shares 的取值范围，超出范围时内核会静默修改为边界值
+const (
+	minCpuShares = 2
+	maxCpuShares = 262144
+)
+
+// Requires reports whether any limit of the subsystem is set, a subsystem without
+// limits is only used for statistics or the default device rules
+func (r *ResourceConfig) Requires(subsystem string) bool {
+	switch subsystem {
+	case "memory":
+		return r.MemoryLimit != 0 || r.MemorySwap != 0 || r.MemoryReservation != 0 || r.MemorySwappiness != nil || r.OomKillDisable
+	case "cpu":
+		return r.CpuShare != "" || r.CpuPeriod != 0 || r.CpuQuota != 0
+	case "cpuset":
+		return r.CpuSet != "" || r.CpusetMems != "" || r.CpusExclusive > 0
+	case "pids":
+		return r.PidsLimit != 0
+	case "blkio":
+		return r.BlkioWeight != 0 || len(r.BlkioDeviceReadBps) > 0 || len(r.BlkioDeviceWriteBps) > 0 ||
+			len(r.BlkioDeviceReadIOps) > 0 || len(r.BlkioDeviceWriteIOps) > 0
+	case "devices":
+		return len(r.Devices) > 0
+	}
+	return false
+}
+
+// Validate checks the resource config before any cgroup is created, so that a limit
+// the kernel cannot enforce is reported up front
+func (r *ResourceConfig) Validate() error {
+	if r.MemoryLimit < 0 || r.MemoryReservation < 0 {
+		return fmt.Errorf("memory limit and reservation must not be negative")
+	}
+	if r.MemorySwap < -1 || (r.MemorySwap > 0 && r.MemorySwap < r.MemoryLimit) {
+		return fmt.Errorf("invalid memory swap %d: must be -1 or at least the memory limit", r.MemorySwap)
+	}
+	if r.MemorySwappiness != nil && (*r.MemorySwappiness < 0 || *r.MemorySwappiness > 100) {
+		return fmt.Errorf("invalid memory swappiness %d: must be between 0 and 100", *r.MemorySwappiness)
+	}
+	if r.OomScoreAdj < -1000 || r.OomScoreAdj > 1000 {
+		return fmt.Errorf("invalid oom score adj %d: must be between -1000 and 1000", r.OomScoreAdj)
+	}
+	if r.CpuShare != "" {
+		shares, err := strconv.ParseUint(r.CpuShare, 10, 64)
+		if err != nil || shares < minCpuShares || shares > maxCpuShares {
+			return fmt.Errorf("invalid cpu share %q: must be between %d and %d", r.CpuShare, minCpuShares, maxCpuShares)
+		}
+	}
+	if r.CpuSet != "" {
+		if _, err := ParseCpuList(r.CpuSet); err != nil {
+			return fmt.Errorf("invalid cpuset %q: should be a list of cpus such as 0-3,5", r.CpuSet)
+		}
+	}
+	if r.CpusExclusive < 0 {
+		return fmt.Errorf("invalid cpus exclusive %d: must not be negative", r.CpusExclusive)
+	}
+	if r.CpusetMems != "" {
+		if _, err := ParseCpuList(r.CpusetMems); err != nil {
+			return fmt.Errorf("invalid cpuset mems %q: should be a list of memory nodes such as 0-1", r.CpusetMems)
+		}
+	}
+	if r.CpuPeriod != 0 && (r.CpuPeriod < 1000 || r.CpuPeriod > 1000000) {
+		return fmt.Errorf("invalid cpu period %d: must be between 1000 and 1000000", r.CpuPeriod)
+	}
+	if r.CpuQuota != 0 && r.CpuQuota != -1 && r.CpuQuota < 1000 {
+		return fmt.Errorf("invalid cpu quota %d: must be at least 1000 or -1", r.CpuQuota)
+	}
+	if r.BlkioWeight != 0 && (r.BlkioWeight < 10 || r.BlkioWeight > 1000) {
+		return fmt.Errorf("invalid blkio weight %d: must be between 10 and 1000", r.BlkioWeight)
+	}
+	if r.PidsLimit < -1 {
+		return fmt.Errorf("invalid pids limit %d: must be -1 or positive", r.PidsLimit)
+	}
+	for _, rule := range r.Devices {
+		if rule.Type != "a" && rule.Type != "b" && rule.Type != "c" {
+			return fmt.Errorf("invalid device rule %q: type must be a, b or c", rule)
+		}
+		if rule.Permissions == "" || strings.Trim(rule.Permissions, "rwm") != "" {
+			return fmt.Errorf("invalid device rule %q: permissions must be a combination of r, w and m", rule)
+		}
+	}
+	return nil
+}
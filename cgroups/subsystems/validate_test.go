@@ -0,0 +1,62 @@
+package subsystems
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCpuList(t *testing.T) {
+	cpus, err := ParseCpuList("3,0-2,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cpus, []int{0, 1, 2, 3}) {
+		t.Errorf("unexpected cpus %v", cpus)
+	}
+	for _, invalid := range []string{"a", "1-", "-1", "3-1", "0,,1", "0-100000"} {
+		if _, err := ParseCpuList(invalid); err == nil {
+			t.Errorf("expect error for cpu list %q", invalid)
+		}
+	}
+}
+
+func TestFormatCpuList(t *testing.T) {
+	if list := FormatCpuList([]int{5, 0, 1, 2, 3, 7, 8}); list != "0-3,5,7-8" {
+		t.Errorf("unexpected cpu list %q", list)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := &ResourceConfig{CpuShare: "512", CpuSet: "0-1", MemoryLimit: 64 << 20, MemorySwap: -1, PidsLimit: -1}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []*ResourceConfig{
+		{CpuShare: "1"},
+		{CpuShare: "300000"},
+		{CpuShare: "abc"},
+		{CpuSet: "0-"},
+		{MemoryLimit: 64 << 20, MemorySwap: 32 << 20},
+		{PidsLimit: -2},
+		{Devices: []DeviceRule{{Type: "x", Major: 1, Minor: 3, Permissions: "rwm"}}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expect error for %+v", invalid)
+		}
+	}
+}
+
+func TestRequires(t *testing.T) {
+	empty := &ResourceConfig{}
+	for _, subsystem := range []string{"memory", "cpu", "cpuset", "cpuacct", "freezer", "pids", "blkio", "devices"} {
+		if empty.Requires(subsystem) {
+			t.Errorf("expect %s not to be required without limits", subsystem)
+		}
+	}
+	res := &ResourceConfig{PidsLimit: -1, BlkioDeviceReadBps: []ThrottleDevice{{Major: 8, Rate: 1024}}}
+	for subsystem, expected := range map[string]bool{"pids": true, "blkio": true, "memory": false, "devices": false} {
+		if res.Requires(subsystem) != expected {
+			t.Errorf("expect %s required to be %v", subsystem, expected)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package subsystems
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// writeVerified writes value to the cgroup file and reads it back, the kernel may clamp,
+// round or ignore a value without returning an error
+func writeVerified(filePath string, value string, accepted func(actual string) bool) error {
+	// cgroupfs 中不存在的文件打开时返回 EACCES，单独报告
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("%s does not exist, the controller is not available", path.Base(filePath))
+	}
+	if err := ioutil.WriteFile(filePath, []byte(value), 0644); err != nil {
+		return err
+	}
+	return verifyFile(filePath, value, accepted)
+}
+
+// verifyFile checks the content of the cgroup file after value was written
+func verifyFile(filePath string, value string, accepted func(actual string) bool) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read back %s fail %v", path.Base(filePath), err)
+	}
+	actual := strings.TrimSpace(string(content))
+	if !accepted(actual) {
+		return fmt.Errorf("kernel did not accept %q, %s is %q", value, path.Base(filePath), actual)
+	}
+	return nil
+}
+
+// ApplyProcess moves every thread of the process into the cgroup and checks that none of
+// them is left outside, tasksFile lists the threads in the cgroup
+func ApplyProcess(cgroupDir string, pid int, tasksFile string) error {
+	// tasks 只移动 pid 对应的线程，Go 程序启动时已经创建了其他线程
+	if err := ioutil.WriteFile(path.Join(cgroupDir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return fmt.Errorf("set cgroup proc fail %v", err)
+	}
+	taskDir := fmt.Sprintf("/proc/%d/task", pid)
+	threads, err := ioutil.ReadDir(taskDir)
+	if err != nil {
+		return fmt.Errorf("list threads of process %d fail %v", pid, err)
+	}
+	content, err := ioutil.ReadFile(path.Join(cgroupDir, tasksFile))
+	if err != nil {
+		return fmt.Errorf("read %s fail %v", tasksFile, err)
+	}
+	tasks := make(map[string]bool)
+	for _, task := range strings.Fields(string(content)) {
+		tasks[task] = true
+	}
+	for _, thread := range threads {
+		if tasks[thread.Name()] {
+			continue
+		}
+		// 列出线程之后退出的线程不会出现在 cgroup 中
+		if _, err := os.Stat(path.Join(taskDir, thread.Name())); os.IsNotExist(err) {
+			continue
+		}
+		return fmt.Errorf("thread %s of process %d is not in cgroup %s", thread.Name(), pid, cgroupDir)
+	}
+	return nil
+}
+
+// writeInt writes a number into a cgroup file and verifies it
+func writeInt(filePath string, value int64) error {
+	content := strconv.FormatInt(value, 10)
+	return writeVerified(filePath, content, sameValue(content))
+}
+
+// writeMemory writes a memory size into a cgroup file, the kernel rounds it down to
+// whole pages and -1 means unlimited
+func writeMemory(filePath string, value int64) error {
+	return writeVerified(filePath, strconv.FormatInt(value, 10), samePages(value))
+}
+
+func sameValue(expected string) func(string) bool {
+	return func(actual string) bool {
+		return actual == expected
+	}
+}
+
+func samePages(expected int64) func(string) bool {
+	pageSize := int64(os.Getpagesize())
+	return func(actual string) bool {
+		// v2 中不限制时为 max
+		if actual == "max" {
+			return expected == -1
+		}
+		num, err := strconv.ParseInt(actual, 10, 64)
+		if err != nil {
+			return false
+		}
+		if expected == -1 {
+			return num == math.MaxInt64&^(pageSize-1)
+		}
+		return num == expected/pageSize*pageSize
+	}
+}
+
+// containsLine accepts the content if one of its lines equals expected
+func containsLine(expected string) func(string) bool {
+	return func(actual string) bool {
+		for _, line := range strings.Split(actual, "\n") {
+			if strings.Join(strings.Fields(line), " ") == expected {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// hasKeyValue accepts the content if one of its "key value" lines has the value
+func hasKeyValue(key string, value string) func(string) bool {
+	return containsLine(key + " " + value)
+}
+
+// sameCpuList accepts the content if it is the same list of cpus or memory nodes,
+// the kernel rewrites "0,1,2" as "0-2"
+func sameCpuList(expected string) func(string) bool {
+	return func(actual string) bool {
+		expectedList, err := ParseCpuList(expected)
+		if err != nil {
+			return false
+		}
+		actualList, err := ParseCpuList(actual)
+		if err != nil || len(actualList) != len(expectedList) {
+			return false
+		}
+		for i := range actualList {
+			if actualList[i] != expectedList[i] {
+				return false
+			}
+		}
+		return true
+	}
+}
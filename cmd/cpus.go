@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/cgroups/subsystems"
+	"MyDocker/container"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// allocateExclusiveCpus picks the exclusive cpus of a new container and stores them
+// in its cpuset
+func allocateExclusiveCpus(containerInfo *container.ContainerInfo) error {
+	res := containerInfo.Resource
+	hostCpus, err := subsystems.EffectiveCpus()
+	if err != nil {
+		return err
+	}
+	cpus, err := container.AllocateExclusiveCpus(containerInfo.Name, res.CpusExclusive, hostCpus, pinnedCpus)
+	if err != nil {
+		return fmt.Errorf("allocate exclusive cpus failed: %v", err)
+	}
+	res.CpuSet = subsystems.FormatCpuList(cpus)
+	return nil
+}
+
+// pinnedCpus returns the cpus in the cpuset of containers which are created with
+// --cpuset-cpus, they can not be used exclusively by another container. Containers
+// not running yet are included, otherwise one started during the allocation is missed
+func pinnedCpus() (map[int]bool, error) {
+	containerInfos, err := listContainerInfos()
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[int]bool)
+	for _, info := range containerInfos {
+		if info.Resource == nil || info.Resource.CpusExclusive > 0 || info.Resource.CpuSet == "" {
+			continue
+		}
+		cpus, err := subsystems.ParseCpuList(info.Resource.CpuSet)
+		if err != nil {
+			return nil, fmt.Errorf("parse cpuset of container %s failed: %v", info.Name, err)
+		}
+		for _, cpu := range cpus {
+			pinned[cpu] = true
+		}
+	}
+	return pinned, nil
+}
+
+// releaseExclusiveCpus gives the exclusive cpus of a container back to the other containers
+func releaseExclusiveCpus(containerName string) error {
+	if err := container.ReleaseExclusiveCpus(containerName); err != nil {
+		return fmt.Errorf("release exclusive cpus failed: %v", err)
+	}
+	rebalanceSharedContainers()
+	return nil
+}
+
+// containerResource returns the resource limits to write into the cgroup of the
+// container, a container without a cpuset is kept off the exclusive cpus of others
+func containerResource(containerInfo *container.ContainerInfo) (*subsystems.ResourceConfig, error) {
+	res := containerInfo.Resource
+	if res.CpusExclusive > 0 {
+		return res, nil
+	}
+	ledger, err := container.ExclusiveCpus()
+	if err != nil {
+		return nil, err
+	}
+	if len(ledger) == 0 {
+		return res, nil
+	}
+	if res.CpuSet != "" {
+		cpus, err := subsystems.ParseCpuList(res.CpuSet)
+		if err != nil {
+			return nil, err
+		}
+		for _, cpu := range cpus {
+			if owner, ok := ledger[cpu]; ok && owner != containerInfo.Name {
+				return nil, fmt.Errorf("cpu %d is used exclusively by container %s", cpu, owner)
+			}
+		}
+		return res, nil
+	}
+
+	hostCpus, err := subsystems.EffectiveCpus()
+	if err != nil {
+		return nil, err
+	}
+	var shared []int
+	for _, cpu := range hostCpus {
+		if _, ok := ledger[cpu]; !ok {
+			shared = append(shared, cpu)
+		}
+	}
+	// 只修改写入 cgroup 的副本，容器信息中仍然不指定 cpuset，独占的 CPU 释放后可以再次使用
+	sharedRes := *res
+	sharedRes.CpuSet = subsystems.FormatCpuList(shared)
+	return &sharedRes, nil
+}
+
+// rebalanceSharedContainers moves the running containers without a cpuset onto the cpus
+// which are not used exclusively, errors are only logged
+func rebalanceSharedContainers() {
+	containerInfos, err := listContainerInfos()
+	if err != nil {
+		log.Warnf("list containers failed: %v", err)
+		return
+	}
+	for _, info := range containerInfos {
+		if info.Status != container.RUNNING && info.Status != container.PAUSED {
+			continue
+		}
+		if info.Resource == nil || info.Resource.CpusExclusive > 0 || info.Resource.CpuSet != "" {
+			continue
+		}
+		res, err := containerResource(info)
+		if err == nil {
+			err = cgroups.NewCgroupManager(info.CgroupPath()).Set(res)
+		}
+		if err != nil {
+			log.Warnf("update cpuset of container %s failed: %v", info.Name, err)
+		}
+	}
+}
@@ -0,0 +1,539 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/cgroups/subsystems"
+	"MyDocker/container"
+	"MyDocker/util"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(createCmd)
+	addContainerFlags(createCmd)
+}
+
+var createCmd = &cobra.Command{
+	Use:   "create IMAGE COMMAND [ARG...]",
+	Short: "Create a new container without starting it",
+	Args:  checkImageAndCommand,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		containerInfo, err := newContainerInfo(cmd, args)
+		if err != nil {
+			return err
+		}
+		if err := createContainer(containerInfo); err != nil {
+			return err
+		}
+		fmt.Println(containerInfo.ID)
+		return nil
+	},
+}
+
+// addContainerFlags adds flags shared by create and run
+func addContainerFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("interactive", "i", false, "keep STDIN open even if not attached")
+	cmd.Flags().BoolP("tty", "t", false, "Allocate a pesudo TTY")
+	cmd.Flags().StringP("volume", "v", "", "Data volume")
+	cmd.Flags().String("name", "", "Container name")
+	cmd.Flags().String("hostname", "", "Container host name, defaults to the container name or ID")
+	cmd.Flags().String("domainname", "", "Container NIS domain name")
+	cmd.Flags().StringSliceP("environment", "e", []string{}, "Environment Set")
+	cmd.Flags().String("net", "", "Container network")
+	cmd.Flags().StringSliceP("port", "p", []string{}, "Port mapping")
+	cmd.Flags().StringP("workdir", "w", "", "Working directory inside the container")
+	cmd.Flags().StringP("user", "u", "", "Username or UID (format: <name|uid>[:<group|gid>])")
+	cmd.Flags().StringSlice("ulimit", []string{}, "Ulimit options (format: <type>=<soft>[:<hard>])")
+	cmd.Flags().String("stop-signal", container.DefaultStopSignal, "Signal to stop the container")
+	cmd.Flags().StringSlice("time-offset", []string{}, "Offset clocks in a new time namespace (format: <monotonic|boottime>=<offset>, e.g. monotonic=+3600s,boottime=+1d)")
+	// resources limit
+	addMemoryFlags(cmd)
+	cmd.Flags().Int("oom-score-adj", 0, "Tune host's OOM preferences (-1000 to 1000)")
+	cmd.Flags().String("cpushare", "", "CPUshare limit")
+	addCpusetFlags(cmd)
+	cmd.Flags().Int("cpus-exclusive", 0, "Number of CPUs allocated exclusively, other containers are kept off them")
+	addCpuQuotaFlags(cmd)
+	cmd.Flags().Int64("pids-limit", 0, "Tune container pids limit (set -1 for unlimited)")
+	cmd.Flags().Uint16("blkio-weight", 0, "Block IO (relative weight), between 10 and 1000, or 0 to disable")
+	cmd.Flags().StringSlice("device-read-bps", []string{}, "Limit read rate (bytes per second) from a device (format: <device-path>:<number>[<unit>])")
+	cmd.Flags().StringSlice("device-write-bps", []string{}, "Limit write rate (bytes per second) to a device (format: <device-path>:<number>[<unit>])")
+	cmd.Flags().StringSlice("device-read-iops", []string{}, "Limit read rate (IO per second) from a device (format: <device-path>:<number>)")
+	cmd.Flags().StringSlice("device-write-iops", []string{}, "Limit write rate (IO per second) to a device (format: <device-path>:<number>)")
+	cmd.Flags().StringSlice("device", []string{}, "Add a host device to the container (format: <host-path>[:<container-path>][:<permissions>])")
+	cmd.Flags().String("cgroup-parent", "", "Optional parent cgroup for the container, e.g. /mydocker/tenantA")
+	cmd.Flags().String("cgroup-parent-memory", "", "Memory limit of all containers in the cgroup parent")
+	cmd.Flags().Float64("cgroup-parent-cpus", 0, "Number of CPUs of all containers in the cgroup parent")
+	cmd.Flags().Int64("cgroup-parent-pids-limit", 0, "Pids limit of all containers in the cgroup parent (set -1 for unlimited)")
+	cmd.Flags().StringSlice("pressure-alert", []string{}, "Emit an event when the container stalls on a resource, cgroup v2 only (format: <cpu|memory|io>=<some|full>:<percent>%)")
+	// flags after IMAGE belong to the container command
+	cmd.Flags().SetInterspersed(false)
+}
+
+// addMemoryFlags adds flags of the memory controller shared by create, run and update
+func addMemoryFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("memory", "m", "", "Memory limit (format: <number>[<unit>], unit can be b, k, m or g)")
+	cmd.Flags().String("memory-swap", "", "Swap limit equal to memory plus swap: '-1' to enable unlimited swap")
+	cmd.Flags().String("memory-reservation", "", "Memory soft limit")
+	cmd.Flags().Int64("memory-swappiness", -1, "Tune container memory swappiness (0 to 100)")
+	cmd.Flags().Bool("oom-kill-disable", false, "Disable OOM Killer")
+}
+
+// 内核能够接受的最小内存限制
+const minMemoryLimit = 6 << 20
+
+// parseMemoryFlags sets the memory limits of res from the flags which are set, all limits
+// are validated before any of them is written to cgroups
+func parseMemoryFlags(cmd *cobra.Command, res *subsystems.ResourceConfig) error {
+	var err error
+	if cmd.Flags().Changed("memory") {
+		memory, _ := cmd.Flags().GetString("memory")
+		if res.MemoryLimit, err = util.RAMInBytes(memory); err != nil {
+			return fmt.Errorf("invalid --memory: %v", err)
+		}
+	}
+	if cmd.Flags().Changed("memory-swap") {
+		memorySwap, _ := cmd.Flags().GetString("memory-swap")
+		if memorySwap == "-1" {
+			res.MemorySwap = -1
+		} else if res.MemorySwap, err = util.RAMInBytes(memorySwap); err != nil {
+			return fmt.Errorf("invalid --memory-swap: %v", err)
+		}
+	}
+	if cmd.Flags().Changed("memory-reservation") {
+		reservation, _ := cmd.Flags().GetString("memory-reservation")
+		if res.MemoryReservation, err = util.RAMInBytes(reservation); err != nil {
+			return fmt.Errorf("invalid --memory-reservation: %v", err)
+		}
+	}
+	if cmd.Flags().Changed("memory-swappiness") {
+		swappiness, _ := cmd.Flags().GetInt64("memory-swappiness")
+		if swappiness < 0 || swappiness > 100 {
+			return fmt.Errorf("invalid --memory-swappiness %d: must be between 0 and 100", swappiness)
+		}
+		res.MemorySwappiness = &swappiness
+	}
+	if cmd.Flags().Changed("oom-kill-disable") {
+		res.OomKillDisable, _ = cmd.Flags().GetBool("oom-kill-disable")
+	}
+
+	if res.MemoryLimit != 0 && res.MemoryLimit < minMemoryLimit {
+		return fmt.Errorf("minimum memory limit allowed is 6MB")
+	}
+	if res.MemorySwap > 0 {
+		if res.MemoryLimit == 0 {
+			return fmt.Errorf("you should always set the memory limit when using memory swap limit")
+		}
+		if res.MemorySwap < res.MemoryLimit {
+			return fmt.Errorf("memory swap limit should be larger than or equal to memory limit")
+		}
+	}
+	if res.MemoryLimit != 0 && res.MemoryReservation > res.MemoryLimit {
+		return fmt.Errorf("memory reservation should be smaller than memory limit")
+	}
+	if res.OomKillDisable && res.MemoryLimit == 0 {
+		log.Warn("disabling the OOM killer on containers without setting a memory limit may be dangerous")
+	}
+	return nil
+}
+
+// parseBlkioFlags sets the block IO weight and throttles of res from flags
+func parseBlkioFlags(cmd *cobra.Command, res *subsystems.ResourceConfig) error {
+	res.BlkioWeight, _ = cmd.Flags().GetUint16("blkio-weight")
+	if res.BlkioWeight != 0 && (res.BlkioWeight < 10 || res.BlkioWeight > 1000) {
+		return fmt.Errorf("invalid --blkio-weight %d: must be between 10 and 1000", res.BlkioWeight)
+	}
+	throttles := []struct {
+		flag    string
+		bps     bool
+		devices *[]subsystems.ThrottleDevice
+	}{
+		{"device-read-bps", true, &res.BlkioDeviceReadBps},
+		{"device-write-bps", true, &res.BlkioDeviceWriteBps},
+		{"device-read-iops", false, &res.BlkioDeviceReadIOps},
+		{"device-write-iops", false, &res.BlkioDeviceWriteIOps},
+	}
+	for _, throttle := range throttles {
+		specs, _ := cmd.Flags().GetStringSlice(throttle.flag)
+		for _, spec := range specs {
+			device, err := parseThrottleDevice(spec, throttle.bps)
+			if err != nil {
+				return fmt.Errorf("invalid --%s %s: %v", throttle.flag, spec, err)
+			}
+			*throttle.devices = append(*throttle.devices, *device)
+		}
+	}
+	return nil
+}
+
+// parseThrottleDevice parses <device-path>:<rate>, the rate of bps can have a unit
+func parseThrottleDevice(spec string, bps bool) (*subsystems.ThrottleDevice, error) {
+	i := strings.LastIndex(spec, ":")
+	if i <= 0 {
+		return nil, fmt.Errorf("expect <device-path>:<rate>")
+	}
+	devicePath, rateStr := spec[:i], spec[i+1:]
+	var rate uint64
+	if bps {
+		bytes, err := util.RAMInBytes(rateStr)
+		if err != nil {
+			return nil, err
+		}
+		rate = uint64(bytes)
+	} else {
+		var err error
+		if rate, err = strconv.ParseUint(rateStr, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid rate %q", rateStr)
+		}
+	}
+	if rate == 0 {
+		return nil, fmt.Errorf("rate must be positive")
+	}
+	return subsystems.NewThrottleDevice(devicePath, rate)
+}
+
+// addCpuQuotaFlags adds flags of the CFS bandwidth limit shared by create, run and update
+func addCpuQuotaFlags(cmd *cobra.Command) {
+	cmd.Flags().Float64("cpus", 0, "Number of CPUs")
+	cmd.Flags().Uint64("cpu-period", 0, "Limit CPU CFS (Completely Fair Scheduler) period")
+	cmd.Flags().Int64("cpu-quota", 0, "Limit CPU CFS (Completely Fair Scheduler) quota")
+}
+
+// parseCpuQuotaFlags sets the CFS quota and period of res from the flags which are set
+func parseCpuQuotaFlags(cmd *cobra.Command, res *subsystems.ResourceConfig) error {
+	if cmd.Flags().Changed("cpu-period") {
+		res.CpuPeriod, _ = cmd.Flags().GetUint64("cpu-period")
+	}
+	if cmd.Flags().Changed("cpu-quota") {
+		res.CpuQuota, _ = cmd.Flags().GetInt64("cpu-quota")
+	}
+	if cmd.Flags().Changed("cpus") {
+		if cmd.Flags().Changed("cpu-quota") {
+			return fmt.Errorf("conflicting options: --cpus and --cpu-quota")
+		}
+		cpus, _ := cmd.Flags().GetFloat64("cpus")
+		if cpus <= 0 {
+			return fmt.Errorf("invalid --cpus %v: must be positive", cpus)
+		}
+		res.CpuQuota, res.CpuPeriod = subsystems.CpusToQuota(cpus, res.CpuPeriod)
+	}
+	// 内核要求 period 在 1ms 到 1s 之间，quota 不小于 1ms
+	if res.CpuPeriod != 0 && (res.CpuPeriod < 1000 || res.CpuPeriod > 1000000) {
+		return fmt.Errorf("invalid cpu period %d: must be between 1000 and 1000000", res.CpuPeriod)
+	}
+	if res.CpuQuota != 0 && res.CpuQuota != -1 && res.CpuQuota < 1000 {
+		return fmt.Errorf("invalid cpu quota %d: must be at least 1000 or -1", res.CpuQuota)
+	}
+	return nil
+}
+
+// addCpusetFlags adds flags of the cpuset controller shared by create, run and update
+func addCpusetFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cpuset-cpus", "", "CPUs in which to allow execution (0-3, 0,1)")
+	cmd.Flags().String("cpuset-mems", "", "Memory nodes (NUMA) in which to allow allocation (0-3, 0,1)")
+	cmd.Flags().String("cpuset", "", "CPUset limit")
+	cmd.Flags().MarkDeprecated("cpuset", "use --cpuset-cpus instead")
+}
+
+// parseCpusetFlags sets the cpus and memory nodes of res from the flags which are set
+func parseCpusetFlags(cmd *cobra.Command, res *subsystems.ResourceConfig) error {
+	if cmd.Flags().Changed("cpuset") {
+		if cmd.Flags().Changed("cpuset-cpus") {
+			return fmt.Errorf("conflicting options: --cpuset and --cpuset-cpus")
+		}
+		res.CpuSet, _ = cmd.Flags().GetString("cpuset")
+	}
+	if cmd.Flags().Changed("cpuset-cpus") {
+		res.CpuSet, _ = cmd.Flags().GetString("cpuset-cpus")
+	}
+	if cmd.Flags().Changed("cpuset-mems") {
+		res.CpusetMems, _ = cmd.Flags().GetString("cpuset-mems")
+	}
+	return nil
+}
+
+func checkImageAndCommand(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("missing container image")
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("missing container command")
+	}
+	return nil
+}
+
+// newContainerInfo builds the config of a new container from flags
+func newContainerInfo(cmd *cobra.Command, args []string) (*container.ContainerInfo, error) {
+	tty, _ := cmd.Flags().GetBool("tty")
+	volume, _ := cmd.Flags().GetString("volume")
+	name, _ := cmd.Flags().GetString("name")
+	network, _ := cmd.Flags().GetString("net")
+	envSlice, _ := cmd.Flags().GetStringSlice("environment")
+	portmapping, _ := cmd.Flags().GetStringSlice("port")
+	workdir, _ := cmd.Flags().GetString("workdir")
+	user, _ := cmd.Flags().GetString("user")
+	ulimits, _ := cmd.Flags().GetStringSlice("ulimit")
+	stopSignal, _ := cmd.Flags().GetString("stop-signal")
+
+	oomScoreAdj, _ := cmd.Flags().GetInt("oom-score-adj")
+	cpuShare, _ := cmd.Flags().GetString("cpushare")
+	pidsLimit, _ := cmd.Flags().GetInt64("pids-limit")
+
+	if _, err := container.ParseSignal(stopSignal); err != nil {
+		return nil, err
+	}
+	if oomScoreAdj < -1000 || oomScoreAdj > 1000 {
+		return nil, fmt.Errorf("invalid --oom-score-adj %d: must be between -1000 and 1000", oomScoreAdj)
+	}
+	res := &subsystems.ResourceConfig{
+		OomScoreAdj: oomScoreAdj,
+		CpuShare:    cpuShare,
+		PidsLimit:   pidsLimit,
+	}
+	if err := parseMemoryFlags(cmd, res); err != nil {
+		return nil, err
+	}
+	if err := parseCpusetFlags(cmd, res); err != nil {
+		return nil, err
+	}
+	if cmd.Flags().Changed("cpus-exclusive") {
+		if res.CpuSet != "" {
+			return nil, fmt.Errorf("conflicting options: --cpus-exclusive and --cpuset-cpus")
+		}
+		res.CpusExclusive, _ = cmd.Flags().GetInt("cpus-exclusive")
+	}
+	if err := parseCpuQuotaFlags(cmd, res); err != nil {
+		return nil, err
+	}
+	if err := parseBlkioFlags(cmd, res); err != nil {
+		return nil, err
+	}
+	spec, err := container.NewInitSpec(args[1:], envSlice, workdir, user, ulimits)
+	if err != nil {
+		return nil, err
+	}
+	devices, _ := cmd.Flags().GetStringSlice("device")
+	for _, deviceSpec := range devices {
+		device, rule, err := container.ParseDevice(deviceSpec)
+		if err != nil {
+			return nil, err
+		}
+		spec.Devices = append(spec.Devices, *device)
+		res.Devices = append(res.Devices, *rule)
+	}
+	spec.Hostname, _ = cmd.Flags().GetString("hostname")
+	spec.Domainname, _ = cmd.Flags().GetString("domainname")
+	if err := container.ValidateUTSNames(spec.Hostname, spec.Domainname); err != nil {
+		return nil, err
+	}
+	timeOffsets, _ := cmd.Flags().GetStringSlice("time-offset")
+	if spec.TimeOffsets, err = container.ParseTimeOffsets(timeOffsets); err != nil {
+		return nil, err
+	}
+	if err := res.Validate(); err != nil {
+		return nil, err
+	}
+	pressureAlerts, err := parsePressureAlerts(cmd)
+	if err != nil {
+		return nil, err
+	}
+	cgroupParent, parentRes, err := parseCgroupParentFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return &container.ContainerInfo{
+		Name:           name,
+		Image:          args[0],
+		Volume:         volume,
+		Network:        network,
+		PortMapping:    portmapping,
+		Tty:            tty,
+		StopSignal:     stopSignal,
+		Spec:           spec,
+		Resource:       res,
+		PressureAlerts: pressureAlerts,
+		CgroupParent:   cgroupParent,
+		ParentResource: parentRes,
+	}, nil
+}
+
+// parseCgroupParentFlags parses --cgroup-parent and the limits of the parent, the
+// returned limits are nil if none of them is set
+func parseCgroupParentFlags(cmd *cobra.Command) (string, *subsystems.ResourceConfig, error) {
+	parent, _ := cmd.Flags().GetString("cgroup-parent")
+	if parent != "" {
+		// 不允许通过 .. 将容器放到 cgroup 根目录之外
+		if parent = path.Clean("/" + parent); parent == "/" {
+			return "", nil, fmt.Errorf("invalid --cgroup-parent: must not be the cgroup root")
+		}
+	}
+	if !cmd.Flags().Changed("cgroup-parent-memory") && !cmd.Flags().Changed("cgroup-parent-cpus") && !cmd.Flags().Changed("cgroup-parent-pids-limit") {
+		return parent, nil, nil
+	}
+	if parent == "" {
+		return "", nil, fmt.Errorf("limits of the cgroup parent require --cgroup-parent")
+	}
+	res := &subsystems.ResourceConfig{}
+	if cmd.Flags().Changed("cgroup-parent-memory") {
+		memory, _ := cmd.Flags().GetString("cgroup-parent-memory")
+		var err error
+		if res.MemoryLimit, err = util.RAMInBytes(memory); err != nil {
+			return "", nil, fmt.Errorf("invalid --cgroup-parent-memory: %v", err)
+		}
+	}
+	if cmd.Flags().Changed("cgroup-parent-cpus") {
+		cpus, _ := cmd.Flags().GetFloat64("cgroup-parent-cpus")
+		if cpus <= 0 {
+			return "", nil, fmt.Errorf("invalid --cgroup-parent-cpus %v: must be positive", cpus)
+		}
+		res.CpuQuota, res.CpuPeriod = subsystems.CpusToQuota(cpus, 0)
+	}
+	res.PidsLimit, _ = cmd.Flags().GetInt64("cgroup-parent-pids-limit")
+	if err := res.Validate(); err != nil {
+		return "", nil, fmt.Errorf("invalid limits of the cgroup parent: %v", err)
+	}
+	return parent, res, nil
+}
+
+// parsePressureAlerts parses --pressure-alert, PSI triggers need cgroup v2
+func parsePressureAlerts(cmd *cobra.Command) ([]cgroups.PressureAlert, error) {
+	specs, _ := cmd.Flags().GetStringSlice("pressure-alert")
+	if len(specs) > 0 && !subsystems.IsCgroup2UnifiedMode() {
+		return nil, fmt.Errorf("--pressure-alert is only supported on cgroup v2")
+	}
+	alerts := make([]cgroups.PressureAlert, 0, len(specs))
+	for _, spec := range specs {
+		alert, err := cgroups.ParsePressureAlert(spec)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, *alert)
+	}
+	return alerts, nil
+}
+
+// createContainer builds the workspace, cgroups and info of a container without starting it
+func createContainer(containerInfo *container.ContainerInfo) (err error) {
+	// generate container ID
+	containerID, err := util.RandString(10)
+	if err != nil {
+		return fmt.Errorf("generate containerID failed: %v", err)
+	}
+	containerInfo.ID = containerID
+	if containerInfo.Name == "" {
+		containerInfo.Name = containerID
+	}
+	containerName := containerInfo.Name
+	if containerInfo.Spec.Hostname == "" {
+		containerInfo.Spec.Hostname = container.DefaultHostname(containerName, containerID)
+	}
+	if util.PathExists(fmt.Sprintf(container.DefaultInfoLocation, containerName)) {
+		return fmt.Errorf("container name %s is already in use", containerName)
+	}
+
+	// 记录创建的每个资源，失败时逆序释放
+	rb := &rollback{}
+	defer func() {
+		if err != nil {
+			rb.run()
+		}
+	}()
+
+	rb.add("container info", func() error {
+		return container.DeleteContainerInfo(containerName)
+	})
+	rb.add("workspace", func() error {
+		container.DeleteWorkSpace(containerInfo.Volume, containerName)
+		return nil
+	})
+	if err := container.CreateWorkSpace(containerInfo.Image, containerName); err != nil {
+		return err
+	}
+
+	if containerInfo.Resource.CpusExclusive > 0 {
+		rb.add("exclusive cpus", func() error {
+			return releaseExclusiveCpus(containerName)
+		})
+		if err := allocateExclusiveCpus(containerInfo); err != nil {
+			return err
+		}
+	}
+
+	rb.add("cgroup parent", func() error {
+		return removeCgroupParent(containerInfo)
+	})
+	if err := setCgroupParent(containerInfo); err != nil {
+		return err
+	}
+
+	// use containerID  as cgroup name
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+	rb.add("cgroups", cgroupManager.Destory)
+	res, err := containerResource(containerInfo)
+	if err != nil {
+		return err
+	}
+	if err := cgroupManager.Set(res); err != nil {
+		return err
+	}
+
+	if err := container.RecordContainerInfo(containerInfo); err != nil {
+		return err
+	}
+	if containerInfo.Resource.CpusExclusive > 0 {
+		// 其他容器让出分配的 CPU
+		rebalanceSharedContainers()
+	}
+	return nil
+}
+
+// deleteContainer releases everything owned by a container which is not running
+func deleteContainer(containerInfo *container.ContainerInfo) error {
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+	cgroupManager.Destory()
+	container.DeleteWorkSpace(containerInfo.Volume, containerInfo.Name)
+	if err := container.DeleteContainerInfo(containerInfo.Name); err != nil {
+		return err
+	}
+	if err := removeCgroupParent(containerInfo); err != nil {
+		return err
+	}
+	if containerInfo.Resource != nil && containerInfo.Resource.CpusExclusive > 0 {
+		return releaseExclusiveCpus(containerInfo.Name)
+	}
+	return nil
+}
+
+// setCgroupParent creates the cgroup parent of the container and sets its limits
+func setCgroupParent(containerInfo *container.ContainerInfo) error {
+	if containerInfo.CgroupParent == "" {
+		return nil
+	}
+	return container.SetCgroupParent(containerInfo.CgroupParent, containerInfo.ParentResource)
+}
+
+// removeCgroupParent removes the cgroup parent of the container when no other container
+// is in it, the parent keeps its limits while a stopped container still uses it
+func removeCgroupParent(containerInfo *container.ContainerInfo) error {
+	parent := containerInfo.CgroupParent
+	if parent == "" {
+		return nil
+	}
+	containerInfos, err := listContainerInfos()
+	if err != nil {
+		return err
+	}
+	var others []string
+	for _, info := range containerInfos {
+		if info.Name != containerInfo.Name && info.CgroupParent != "" {
+			others = append(others, info.CgroupParent)
+		}
+	}
+	return container.RemoveCgroupParent(parent, others)
+}
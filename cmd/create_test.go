@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"MyDocker/cgroups/subsystems"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func parseTestCgroupParentFlags(t *testing.T, args ...string) (string, *subsystems.ResourceConfig, error) {
+	cmd := &cobra.Command{}
+	addContainerFlags(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+	return parseCgroupParentFlags(cmd)
+}
+
+func TestParseCgroupParentFlags(t *testing.T) {
+	// 路径被规范为绝对路径，且不能通过 .. 离开 cgroup 根目录
+	if parent, res, err := parseTestCgroupParentFlags(t, "--cgroup-parent", "mydocker/../../tenantA/"); err != nil || parent != "/tenantA" || res != nil {
+		t.Errorf("unexpected parent %q, limits %+v, err %v", parent, res, err)
+	}
+	if parent, res, err := parseTestCgroupParentFlags(t); err != nil || parent != "" || res != nil {
+		t.Errorf("unexpected parent %q, limits %+v, err %v", parent, res, err)
+	}
+	parent, res, err := parseTestCgroupParentFlags(t, "--cgroup-parent", "/mydocker/tenantA",
+		"--cgroup-parent-memory", "100m", "--cgroup-parent-cpus", "1.5", "--cgroup-parent-pids-limit", "100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent != "/mydocker/tenantA" || res.MemoryLimit != 100*1024*1024 || res.CpuQuota != 150000 || res.PidsLimit != 100 {
+		t.Errorf("unexpected parent %q, limits %+v", parent, res)
+	}
+
+	for _, args := range [][]string{
+		{"--cgroup-parent", "/.."},
+		{"--cgroup-parent-memory", "100m"},
+		{"--cgroup-parent", "/mydocker", "--cgroup-parent-cpus", "0"},
+		{"--cgroup-parent", "/mydocker", "--cgroup-parent-memory", "100x"},
+	} {
+		if _, _, err := parseTestCgroupParentFlags(t, args...); err == nil {
+			t.Errorf("expect error for %v", args)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"MyDocker/container"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+}
+
+var eventsCmd = &cobra.Command{
+	Use:   "events [NAME...]",
+	Short: "Print events of containers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return printEvents(args)
+	},
+}
+
+// printEvents prints events of the given containers, or all events if no container is given
+func printEvents(containerNames []string) error {
+	events, err := container.ReadEvents()
+	if err != nil {
+		return err
+	}
+	filter := make(map[string]bool)
+	for _, name := range containerNames {
+		filter[name] = true
+	}
+	for _, event := range events {
+		if len(filter) > 0 && !filter[event.Name] {
+			continue
+		}
+		attributes := []string{"id=" + event.ID}
+		keys := make([]string, 0, len(event.Attributes))
+		for key := range event.Attributes {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			attributes = append(attributes, key+"="+event.Attributes[key])
+		}
+		fmt.Printf("%s container %s %s (%s)\n", event.Time, event.Type, event.Name, strings.Join(attributes, ", "))
+	}
+	return nil
+}
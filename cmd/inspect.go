@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/container"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+}
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect NAME",
+	Short: "Display detailed information of a container",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("missing container name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return inspectContainer(args[0])
+	},
+}
+
+// containerInspect 是 inspect 输出的内容，运行中的容器附带当前的 PSI
+type containerInspect struct {
+	*container.ContainerInfo
+	Pressure *cgroups.Pressure `json:"pressure,omitempty"`
+}
+
+func inspectContainer(containerName string) error {
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		return fmt.Errorf("get container info failed: %v", err)
+	}
+	result := &containerInspect{ContainerInfo: containerInfo}
+	if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
+		if stats, err := cgroups.NewCgroupManager(containerInfo.CgroupPath()).GetStats(); err == nil {
+			result.Pressure = stats.Pressure
+		}
+	}
+	content, err := json.MarshalIndent(result, "", "    ")
+	if err != nil {
+		return fmt.Errorf("marshal container info failed: %v", err)
+	}
+	fmt.Println(string(content))
+	return nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"MyDocker/container"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+var killCmd = &cobra.Command{
+	Use:   "kill NAME",
+	Short: "Send a signal to a running container",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("missing container name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		signal, _ := cmd.Flags().GetString("signal")
+		return killContainer(args[0], signal)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(killCmd)
+	killCmd.Flags().StringP("signal", "s", "SIGKILL", "Signal to send to the container")
+}
+
+// killContainer sends the signal to the container process, a paused container is
+// unpaused first, the exit of the process is recorded by its monitor
+func killContainer(containerName string, signal string) error {
+	sig, err := container.ParseSignal(signal)
+	if err != nil {
+		return err
+	}
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		return fmt.Errorf("get container info failed: %v", err)
+	}
+	if containerInfo.Status != container.RUNNING && containerInfo.Status != container.PAUSED {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+	// 被冻结的进程恢复后才能处理信号，先恢复再发送，避免容器退出后状态被改回 running
+	if containerInfo.Status == container.PAUSED {
+		if err := unpauseContainer(containerName); err != nil {
+			return err
+		}
+	}
+	pid, _ := strconv.Atoi(containerInfo.PID)
+	if err := signalContainer(pid, sig); err != nil {
+		return fmt.Errorf("kill container failed: %v", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"MyDocker/cgroups"
 	"MyDocker/container"
 	"MyDocker/util"
 	"encoding/json"
@@ -9,6 +10,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
@@ -27,29 +29,60 @@ func init() {
 }
 
 func ListContainers() error {
+	containerInfos, err := listContainerInfos()
+	if err != nil {
+		return err
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "NAME", "PID", "STATUS", "PIDS", "COMMAND", "CREATED"})
+	for _, containerInfo := range containerInfos {
+		table.Append([]string{containerInfo.ID, containerInfo.Name, containerInfo.PID, containerInfo.Status, getPidsUsage(containerInfo), containerInfo.Command, containerInfo.CreatedTime})
+	}
+	table.Render()
+	return nil
+}
+
+// getPidsUsage returns the number of processes against the pids limit of a running container
+func getPidsUsage(containerInfo *container.ContainerInfo) string {
+	if containerInfo.Status != container.RUNNING && containerInfo.Status != container.PAUSED {
+		return ""
+	}
+	stats, err := cgroups.NewCgroupManager(containerInfo.CgroupPath()).GetStats()
+	if err != nil {
+		return ""
+	}
+	limit := "max"
+	if containerInfo.Resource != nil && containerInfo.Resource.PidsLimit > 0 {
+		limit = strconv.FormatInt(containerInfo.Resource.PidsLimit, 10)
+	}
+	return fmt.Sprintf("%d/%s", stats.Pids, limit)
+}
+
+// listContainerInfos reads the info of all containers
+func listContainerInfos() ([]*container.ContainerInfo, error) {
 	infoDir := path.Dir(fmt.Sprintf(container.DefaultInfoLocation, ""))
 	if !util.PathExists(infoDir) {
 		os.Mkdir(infoDir, 0666)
 	}
 	files, err := ioutil.ReadDir(infoDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"ID", "NAME", "PID", "STATUS", "COMMAND", "CREATED"})
+	var containerInfos []*container.ContainerInfo
 	for _, file := range files {
-		if file.Name() == "network" {
+		// 跳过网络配置和事件日志等不属于容器的文件
+		if !file.IsDir() || file.Name() == "network" {
 			continue
 		}
 		containerInfo, err := getContainerInfo(file)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		table.Append([]string{containerInfo.ID, containerInfo.Name, containerInfo.PID, containerInfo.Status, containerInfo.Command, containerInfo.CreatedTime})
+		containerInfos = append(containerInfos, containerInfo)
 	}
-	table.Render()
-	return nil
+	return containerInfos, nil
 }
 
 func getContainerInfo(file fs.FileInfo) (*container.ContainerInfo, error) {
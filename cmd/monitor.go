@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/container"
+	"MyDocker/network"
+	"MyDocker/reexec"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// monitor 进程的 fd 3 用于回传容器进程 pid，之后的 fd 原样转交给容器 init 进程
+const monitorPidPipeFd = 3
+
+// startContainerMonitor starts a monitor process which becomes the parent of the
+// container init process, so the exit status can still be collected after `run` returns.
+// It returns the monitor command and the host pid of the container init process.
+func startContainerMonitor(initCmd *exec.Cmd, containerName string) (*exec.Cmd, int, error) {
+	pidReadPipe, pidWritePipe, err := os.Pipe()
+	if err != nil {
+		return nil, 0, fmt.Errorf("new pipe failed: %v", err)
+	}
+	defer pidReadPipe.Close()
+
+	cmd := reexec.Command("containerMonitorProcess", containerName, strconv.Itoa(len(initCmd.ExtraFiles)))
+	// 脱离当前会话，mydocker run 退出后 monitor 继续运行
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.Stdout = initCmd.Stdout
+	cmd.Stderr = initCmd.Stderr
+	cmd.Env = initCmd.Env
+	cmd.Dir = initCmd.Dir
+	cmd.ExtraFiles = append([]*os.File{pidWritePipe}, initCmd.ExtraFiles...)
+	err = cmd.Start()
+	pidWritePipe.Close()
+	if err != nil {
+		return nil, 0, fmt.Errorf("start monitor process failed: %v", err)
+	}
+
+	content, err := ioutil.ReadAll(pidReadPipe)
+	if err != nil {
+		return nil, 0, fmt.Errorf("read container pid failed: %v", err)
+	}
+	pid, err := strconv.Atoi(string(content))
+	if err != nil {
+		cmd.Wait()
+		return nil, 0, fmt.Errorf("monitor failed to start container process")
+	}
+	return cmd, pid, nil
+}
+
+// runContainerMonitorProcess is the entry of the monitor process, it starts the
+// container init process, waits for it and records how it exited.
+func runContainerMonitorProcess() {
+	if len(os.Args) < 3 {
+		log.Error("container monitor failed: missing arguments")
+		return
+	}
+	containerName := os.Args[1]
+	fileCount, err := strconv.Atoi(os.Args[2])
+	if err != nil {
+		log.Errorf("container monitor failed: %v", err)
+		return
+	}
+
+	// 容器运行期间一直持有 monitor 锁
+	lock, err := container.LockContainerMonitor(containerName)
+	if err != nil {
+		log.Errorf("container monitor failed: %v", err)
+		return
+	}
+	defer lock.Close()
+
+	// 继承的 fd 没有 CLOEXEC，避免泄漏到容器进程中
+	for fd := monitorPidPipeFd; fd <= monitorPidPipeFd+fileCount; fd++ {
+		syscall.CloseOnExec(fd)
+	}
+	pidPipe := os.NewFile(uintptr(monitorPidPipeFd), "pid")
+	extraFiles := make([]*os.File, 0, fileCount)
+	for i := 1; i <= fileCount; i++ {
+		extraFiles = append(extraFiles, os.NewFile(uintptr(monitorPidPipeFd+i), "init"))
+	}
+
+	initCmd := container.NewInitCommand()
+	initCmd.Stdout = os.Stdout
+	initCmd.Stderr = os.Stderr
+	initCmd.Env = os.Environ()
+	initCmd.ExtraFiles = extraFiles
+	err = initCmd.Start()
+	for _, file := range extraFiles {
+		file.Close()
+	}
+	if err != nil {
+		log.Errorf("start container process failed: %v", err)
+		pidPipe.Close()
+		return
+	}
+	pidPipe.WriteString(strconv.Itoa(initCmd.Process.Pid))
+	pidPipe.Close()
+
+	// 离开容器的挂载点，否则容器退出后无法卸载 workspace
+	if err := os.Chdir("/"); err != nil {
+		log.Error(err)
+	}
+
+	waitContainer(containerName, initCmd)
+}
+
+// waitContainer waits for the container process while watching OOM and pressure of its cgroup, then
+// finishes the container
+func waitContainer(containerName string, cmd *exec.Cmd) {
+	stopWatchingOOM := watchContainerOOM(containerName)
+	stopWatchingPressure := watchContainerPressure(containerName)
+	exitCode := waitContainerProcess(cmd)
+	stopWatchingPressure()
+	stopWatchingOOM()
+	finishContainer(containerName, exitCode)
+}
+
+// watchContainerOOM records every OOM of the container until the returned function is called
+func watchContainerOOM(containerName string) func() {
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		log.Errorf("watch container OOM failed: %v", err)
+		return func() {}
+	}
+	notifier, err := cgroups.NewCgroupManager(containerInfo.CgroupPath()).NotifyOOM()
+	if err != nil {
+		log.Errorf("watch container OOM failed: %v", err)
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range notifier.C {
+			containerInfo, err := container.GetContainerInfo(containerName)
+			if err != nil {
+				log.Error(err)
+				continue
+			}
+			recordContainerOOM(containerInfo, true)
+		}
+	}()
+	return func() {
+		notifier.Close()
+		<-done
+	}
+}
+
+// watchContainerPressure emits an event every time the container exceeds one of its
+// pressure alerts until the returned function is called
+func watchContainerPressure(containerName string) func() {
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		log.Errorf("watch container pressure failed: %v", err)
+		return func() {}
+	}
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+	var notifiers []*cgroups.PressureNotifier
+	var wg sync.WaitGroup
+	for _, alert := range containerInfo.PressureAlerts {
+		notifier, err := cgroupManager.NotifyPressure(alert)
+		if err != nil {
+			log.Errorf("watch container pressure failed: %v", err)
+			continue
+		}
+		notifiers = append(notifiers, notifier)
+		wg.Add(1)
+		go func(alert cgroups.PressureAlert) {
+			defer wg.Done()
+			for range notifier.C {
+				attributes := map[string]string{
+					"resource": alert.Resource,
+					"alert":    alert.String(),
+				}
+				// 附带当前的 avg10，便于判断 stall 的程度
+				if stats, err := cgroupManager.GetStats(); err == nil && stats.Pressure != nil {
+					if psi := pressureOf(stats.Pressure, alert.Resource); psi != nil {
+						avg10 := psi.Some.Avg10
+						if alert.Type == "full" {
+							avg10 = psi.Full.Avg10
+						}
+						attributes["avg10"] = fmt.Sprintf("%.2f%%", avg10)
+					}
+				}
+				if err := container.EmitEvent(containerInfo, container.EventPressure, attributes); err != nil {
+					log.Error(err)
+				}
+			}
+		}(alert)
+	}
+	return func() {
+		for _, notifier := range notifiers {
+			notifier.Close()
+		}
+		wg.Wait()
+	}
+}
+
+// pressureOf returns PSI of the resource
+func pressureOf(pressure *cgroups.Pressure, resource string) *cgroups.PSIStats {
+	switch resource {
+	case "cpu":
+		return pressure.Cpu
+	case "memory":
+		return pressure.Memory
+	case "io":
+		return pressure.Io
+	}
+	return nil
+}
+
+// recordContainerOOM updates the OOM kill count of the container from its cgroup, an OOM
+// event is emitted if notified is set or more processes have been killed since last record
+func recordContainerOOM(containerInfo *container.ContainerInfo, notified bool) {
+	stats, err := cgroups.NewCgroupManager(containerInfo.CgroupPath()).GetStats()
+	if err != nil {
+		log.Errorf("get container OOM kill count failed: %v", err)
+		return
+	}
+	if !notified && stats.OOMKills <= containerInfo.OOMKillCount {
+		return
+	}
+	containerInfo.OOMKillCount = stats.OOMKills
+	containerInfo.OOMKilled = stats.OOMKills > 0
+	// 只修改 OOM 的记录，monitor 运行期间 stop、pause 等命令也在修改容器信息
+	if _, err := container.ModifyContainerInfo(containerInfo.Name, func(info *container.ContainerInfo) error {
+		info.OOMKillCount = containerInfo.OOMKillCount
+		info.OOMKilled = containerInfo.OOMKilled
+		return nil
+	}); err != nil {
+		log.Error(err)
+	}
+	attributes := map[string]string{"oomKillCount": strconv.FormatUint(stats.OOMKills, 10)}
+	if err := container.EmitEvent(containerInfo, container.EventOOM, attributes); err != nil {
+		log.Error(err)
+	}
+}
+
+// waitContainerProcess waits for the container process and returns its exit code,
+// a process killed by signal exits with 128+signal like a shell does
+func waitContainerProcess(cmd *exec.Cmd) int {
+	cmd.Wait()
+	status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+	if !ok {
+		return cmd.ProcessState.ExitCode()
+	}
+	if status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return status.ExitStatus()
+}
+
+// finishContainer releases resources of an exited container and records its exit code
+func finishContainer(containerName string, exitCode int) {
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		log.Errorf("finish container failed: %v", err)
+		return
+	}
+	// 补上退出前没来得及处理的 OOM，cgroup 释放后无法再读取
+	recordContainerOOM(containerInfo, false)
+	releaseContainerResources(containerInfo)
+	if err := container.RecordContainerExit(containerName, exitCode); err != nil {
+		log.Error(err)
+	}
+}
+
+// releaseContainerResources releases cgroups, network and the mounted workspace of
+// an exited container, the write layer is kept for the next start
+func releaseContainerResources(containerInfo *container.ContainerInfo) {
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+	cgroupManager.Destory()
+	if containerInfo.Network != "" && containerInfo.IPAddress != "" {
+		if err := network.Init(); err != nil {
+			log.Error(err)
+		} else if err := network.Disconnect(containerInfo.Network, containerInfo); err != nil {
+			log.Errorf("disconnect container network failed: %v", err)
+		}
+		containerInfo.IPAddress = ""
+	}
+	container.UnmountWorkSpace(containerInfo.Volume, containerInfo.Name)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/container"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause NAME",
+	Short: "Pause all processes within a container",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("missing container name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return pauseContainer(args[0])
+	},
+}
+
+var unpauseCmd = &cobra.Command{
+	Use:   "unpause NAME",
+	Short: "Unpause all processes within a container",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("missing container name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return unpauseContainer(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(unpauseCmd)
+}
+
+// pauseContainer freezes every process in the cgroup of the container
+func pauseContainer(containerName string) error {
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		return fmt.Errorf("get container info failed: %v", err)
+	}
+	if containerInfo.Status != container.RUNNING {
+		return fmt.Errorf("container %s is not running", containerName)
+	}
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+	if err := cgroupManager.Freeze(); err != nil {
+		// 冻结失败时恢复已经冻结的进程
+		cgroupManager.Thaw()
+		return fmt.Errorf("pause container failed: %v", err)
+	}
+	_, err = container.ModifyContainerInfo(containerName, func(info *container.ContainerInfo) error {
+		info.Status = container.PAUSED
+		return nil
+	})
+	return err
+}
+
+// unpauseContainer thaws every process in the cgroup of the container
+func unpauseContainer(containerName string) error {
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		return fmt.Errorf("get container info failed: %v", err)
+	}
+	if containerInfo.Status != container.PAUSED {
+		return fmt.Errorf("container %s is not paused", containerName)
+	}
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+	if err := cgroupManager.Thaw(); err != nil {
+		return fmt.Errorf("unpause container failed: %v", err)
+	}
+	_, err = container.ModifyContainerInfo(containerName, func(info *container.ContainerInfo) error {
+		info.Status = container.RUNNING
+		return nil
+	})
+	return err
+}
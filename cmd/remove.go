@@ -3,9 +3,7 @@ package cmd
 import (
 	"MyDocker/container"
 	"fmt"
-	"os"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -29,18 +27,12 @@ func init() {
 }
 
 func removeContainer(containerName string) error {
-	containerInfo, err := getContainInfoByName(containerName)
+	containerInfo, err := container.GetContainerInfo(containerName)
 	if err != nil {
 		return fmt.Errorf("get container info failed: %v", err)
 	}
-	if containerInfo.Status != container.STOP {
+	if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
 		return fmt.Errorf("couldn't remove running container")
 	}
-
-	containerDir := fmt.Sprintf(container.DefaultInfoLocation, containerName)
-	if err := os.RemoveAll(containerDir); err != nil {
-		log.Errorf("remove file failed: %v", err)
-	}
-	container.DeleteWorkSpace(containerInfo.Volume, containerName)
-	return nil
+	return deleteContainer(containerInfo)
 }
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"MyDocker/container"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var restartCmd = &cobra.Command{
+	Use:   "restart NAME",
+	Short: "Restart a container",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("missing container name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		timeout, _ := cmd.Flags().GetInt("time")
+		return restartContainer(args[0], time.Duration(timeout)*time.Second)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restartCmd)
+	restartCmd.Flags().IntP("time", "t", defaultStopTimeout, "Seconds to wait for stop before killing it")
+}
+
+func restartContainer(containerName string, timeout time.Duration) error {
+	containerInfo, err := container.GetContainerInfo(containerName)
+	if err != nil {
+		return fmt.Errorf("get container info failed: %v", err)
+	}
+	if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
+		if err := stopContainer(containerName, timeout); err != nil {
+			return err
+		}
+		if containerInfo, err = container.GetContainerInfo(containerName); err != nil {
+			return fmt.Errorf("get container info failed: %v", err)
+		}
+	}
+	return startContainer(containerInfo)
+}
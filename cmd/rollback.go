@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// rollback records how to undo each resource created so far, so that a
+// failed command can tear them down in reverse order
+type rollback struct {
+	steps []rollbackStep
+}
+
+type rollbackStep struct {
+	name string
+	undo func() error
+}
+
+// add records the undo step of a resource which has just been created
+func (r *rollback) add(name string, undo func() error) {
+	r.steps = append(r.steps, rollbackStep{name: name, undo: undo})
+}
+
+// run undoes all recorded steps in reverse order, errors are logged and
+// do not stop the remaining steps
+func (r *rollback) run() {
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+		if err := step.undo(); err != nil {
+			log.Errorf("rollback %s failed: %v", step.name, err)
+		}
+	}
+	r.steps = nil
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/container"
+	"MyDocker/network"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// 启动容器前等待上一次运行的 monitor 释放资源的最长时间
+const monitorWaitTimeout = 10 * time.Second
+
+var startCmd = &cobra.Command{
+	Use:   "start NAME",
+	Short: "Start a created or stopped container",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("missing container name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		containerInfo, err := container.GetContainerInfo(args[0])
+		if err != nil {
+			return fmt.Errorf("get container info failed: %v", err)
+		}
+		if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
+			return fmt.Errorf("container %s is already running", containerInfo.Name)
+		}
+		return startContainer(containerInfo)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(startCmd)
+}
+
+// startContainer starts the process of a created or stopped container with its stored
+// config. Detached containers are waited by a monitor process, a tty container is
+// waited in the foreground.
+func startContainer(containerInfo *container.ContainerInfo) (err error) {
+	containerName := containerInfo.Name
+	if err := container.WaitContainerMonitor(containerName, monitorWaitTimeout); err != nil {
+		return err
+	}
+
+	// 记录启动过程中创建的资源，失败时逆序释放
+	rb := &rollback{}
+	defer func() {
+		if err != nil {
+			rb.run()
+		}
+	}()
+
+	rb.add("workspace", func() error {
+		container.UnmountWorkSpace(containerInfo.Volume, containerName)
+		return nil
+	})
+	if err := container.MountWorkSpace(containerInfo.Volume, containerInfo.Image, containerName); err != nil {
+		return err
+	}
+
+	// cgroup parent 在最后一个容器删除时才删除，这里只重新设置其限制
+	if err := setCgroupParent(containerInfo); err != nil {
+		return err
+	}
+	// use containerID  as cgroup name
+	cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+	rb.add("cgroups", cgroupManager.Destory)
+	res, err := containerResource(containerInfo)
+	if err != nil {
+		return err
+	}
+	if err := cgroupManager.Set(res); err != nil {
+		return err
+	}
+
+	containerProcess, writePipe, syncPipe, err := container.NewContainerProcess(containerInfo.Tty, containerName)
+	if err != nil {
+		return err
+	}
+	var pid int
+	var monitorProcess *exec.Cmd
+	if containerInfo.Tty {
+		// 前台容器由当前进程等待其退出
+		lock, lockErr := container.LockContainerMonitor(containerName)
+		if lockErr != nil {
+			return lockErr
+		}
+		defer lock.Close()
+		err = containerProcess.Start()
+		if err == nil {
+			pid = containerProcess.Process.Pid
+		}
+	} else {
+		// 后台容器由 monitor 进程启动并等待其退出
+		monitorProcess, pid, err = startContainerMonitor(containerProcess, containerName)
+	}
+	// 关闭属于子进程的 pipe 端，否则读取 sync pipe 时无法收到 EOF
+	for _, file := range containerProcess.ExtraFiles {
+		file.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("start container process failed: %v", err)
+	}
+	rb.add("container process", func() error {
+		if containerInfo.Tty {
+			containerProcess.Process.Kill()
+			finishContainer(containerName, waitContainerProcess(containerProcess))
+			return nil
+		}
+		// 等待 monitor 释放资源后退出，避免其与回滚同时释放资源
+		syscall.Kill(pid, syscall.SIGKILL)
+		return monitorProcess.Wait()
+	})
+
+	// init 进程阻塞在读取 init spec 上，确认其所有线程都加入 cgroup 后才发送 init spec，
+	// 因此容器的命令从开始执行时就受到资源限制
+	if err := cgroupManager.Apply(pid); err != nil {
+		return err
+	}
+	if containerInfo.Resource.OomScoreAdj != 0 {
+		if err := setOomScoreAdj(pid, containerInfo.Resource.OomScoreAdj); err != nil {
+			return err
+		}
+	}
+
+	if _, err := container.ModifyContainerInfo(containerName, func(info *container.ContainerInfo) error {
+		info.PID = strconv.Itoa(pid)
+		info.Status = container.RUNNING
+		// cgroup 每次启动时重新创建，OOM 只统计本次运行
+		info.OOMKilled = false
+		info.OOMKillCount = 0
+		return nil
+	}); err != nil {
+		return err
+	}
+	containerInfo.PID = strconv.Itoa(pid)
+
+	if containerInfo.Network != "" {
+		// config network
+		if err := network.Init(); err != nil {
+			return err
+		}
+		if err := network.Connect(containerInfo.Network, containerInfo); err != nil {
+			return fmt.Errorf("config network failed: %v", err)
+		}
+		// 容器退出后根据记录的 IP 释放网络资源
+		if _, err := container.ModifyContainerInfo(containerName, func(info *container.ContainerInfo) error {
+			info.IPAddress = containerInfo.IPAddress
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := container.SendInitSpec(containerInfo.Spec, writePipe); err != nil {
+		return err
+	}
+	// 等待 init 进程完成初始化，失败时返回出错的阶段
+	if err := container.WaitInitProcess(syncPipe); err != nil {
+		return err
+	}
+	if len(containerInfo.Spec.TimeOffsets) > 0 {
+		if err := container.VerifyTimeNamespace(pid, containerInfo.Spec.TimeOffsets); err != nil {
+			return err
+		}
+	}
+	if containerInfo.Tty {
+		waitContainer(containerName, containerProcess)
+	}
+	return nil
+}
+
+// setOomScoreAdj sets oom_score_adj of the container process, which is inherited by
+// all processes it forks
+func setOomScoreAdj(pid int, oomScoreAdj int) error {
+	oomScoreAdjPath := fmt.Sprintf("/proc/%d/oom_score_adj", pid)
+	if err := ioutil.WriteFile(oomScoreAdjPath, []byte(strconv.Itoa(oomScoreAdj)), 0644); err != nil {
+		return fmt.Errorf("set oom score adj failed: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/container"
+	"MyDocker/network"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// 两次采样的间隔，也是实时刷新的间隔
+const statsInterval = time.Second
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.Flags().Bool("no-stream", false, "Disable streaming stats and only pull the first result")
+	statsCmd.Flags().String("format", "", "Format the output, only json is supported")
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [NAME...]",
+	Short: "Display a live stream of container resource usage",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		noStream, _ := cmd.Flags().GetBool("no-stream")
+		format, _ := cmd.Flags().GetString("format")
+		if format != "" && format != "json" {
+			return fmt.Errorf("unsupported format: %s", format)
+		}
+		cmd.SilenceUsage = true
+
+		var containerInfos []*container.ContainerInfo
+		if len(args) == 0 {
+			// 默认显示所有运行中的容器
+			infos, err := listContainerInfos()
+			if err != nil {
+				return err
+			}
+			for _, info := range infos {
+				if info.Status == container.RUNNING || info.Status == container.PAUSED {
+					containerInfos = append(containerInfos, info)
+				}
+			}
+		} else {
+			for _, containerName := range args {
+				info, err := container.GetContainerInfo(containerName)
+				if err != nil {
+					return fmt.Errorf("get container info failed: %v", err)
+				}
+				containerInfos = append(containerInfos, info)
+			}
+		}
+		return statsContainers(containerInfos, !noStream, format == "json")
+	},
+}
+
+// containerStats 是一个容器在一次采样间隔内的资源使用情况
+type containerStats struct {
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	CpuPercent    float64           `json:"cpuPercent"`
+	MemoryUsage   uint64            `json:"memoryUsage"`
+	MemoryLimit   uint64            `json:"memoryLimit"`
+	MemoryPercent float64           `json:"memoryPercent"`
+	NetRx         uint64            `json:"netRx"`
+	NetTx         uint64            `json:"netTx"`
+	BlockRead     uint64            `json:"blockRead"`
+	BlockWrite    uint64            `json:"blockWrite"`
+	Pids          uint64            `json:"pids"`
+	Pressure      *cgroups.Pressure `json:"pressure,omitempty"`
+}
+
+// statsContainers samples the containers every interval and prints their usage, it
+// prints only once if stream is not set
+func statsContainers(containerInfos []*container.ContainerInfo, stream bool, jsonFormat bool) error {
+	for _, info := range containerInfos {
+		if info.Network != "" {
+			if err := network.Init(); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	// CPU 使用率需要两次采样之间的 CPU 时间差
+	prevStats := make(map[string]*cgroups.Stats)
+	prevTime := time.Now()
+	for _, info := range containerInfos {
+		prevStats[info.Name], _ = cgroups.NewCgroupManager(info.CgroupPath()).GetStats()
+	}
+	for {
+		time.Sleep(statsInterval)
+		now := time.Now()
+		interval := now.Sub(prevTime)
+		prevTime = now
+
+		results := make([]*containerStats, 0, len(containerInfos))
+		for _, info := range containerInfos {
+			stats, err := cgroups.NewCgroupManager(info.CgroupPath()).GetStats()
+			if err != nil {
+				// 已经退出的容器没有 cgroup，显示为 0
+				log.Debugf("get container %s stats failed: %v", info.Name, err)
+				stats = &cgroups.Stats{}
+			}
+			results = append(results, newContainerStats(info, prevStats[info.Name], stats, interval))
+			prevStats[info.Name] = stats
+		}
+
+		if jsonFormat {
+			for _, result := range results {
+				content, err := json.Marshal(result)
+				if err != nil {
+					return fmt.Errorf("marshal container stats failed: %v", err)
+				}
+				fmt.Println(string(content))
+			}
+		} else {
+			if stream {
+				// 清屏后从左上角重新输出
+				fmt.Print("\033[2J\033[H")
+			}
+			printContainerStats(results)
+		}
+		if !stream {
+			return nil
+		}
+	}
+}
+
+func newContainerStats(info *container.ContainerInfo, prev, current *cgroups.Stats, interval time.Duration) *containerStats {
+	result := &containerStats{
+		ID:          info.ID,
+		Name:        info.Name,
+		MemoryUsage: current.MemoryUsage,
+		MemoryLimit: current.MemoryLimit,
+		BlockRead:   current.BlkioRead,
+		BlockWrite:  current.BlkioWrite,
+		Pids:        current.Pids,
+		Pressure:    current.Pressure,
+	}
+	if prev != nil && current.CpuUsage > prev.CpuUsage {
+		result.CpuPercent = float64(current.CpuUsage-prev.CpuUsage) / float64(interval.Nanoseconds()) * 100
+	}
+	// 不限制内存时以 host 的内存总量作为上限
+	if result.MemoryLimit == 0 {
+		var sysinfo syscall.Sysinfo_t
+		if err := syscall.Sysinfo(&sysinfo); err == nil {
+			result.MemoryLimit = sysinfo.Totalram * uint64(sysinfo.Unit)
+		}
+	}
+	if result.MemoryLimit != 0 {
+		result.MemoryPercent = float64(result.MemoryUsage) / float64(result.MemoryLimit) * 100
+	}
+	if info.Network != "" && info.IPAddress != "" {
+		if netStats, err := network.GetEndpointStats(info.Network, info); err == nil {
+			result.NetRx = netStats.RxBytes
+			result.NetTx = netStats.TxBytes
+		}
+	}
+	return result
+}
+
+func printContainerStats(results []*containerStats) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "NAME", "CPU %", "MEM USAGE / LIMIT", "MEM %", "NET I/O", "BLOCK I/O", "PIDS", "PSI CPU / MEM / IO"})
+	for _, result := range results {
+		table.Append([]string{
+			result.ID,
+			result.Name,
+			fmt.Sprintf("%.2f%%", result.CpuPercent),
+			fmt.Sprintf("%s / %s", formatBytes(result.MemoryUsage), formatBytes(result.MemoryLimit)),
+			fmt.Sprintf("%.2f%%", result.MemoryPercent),
+			fmt.Sprintf("%s / %s", formatBytes(result.NetRx), formatBytes(result.NetTx)),
+			fmt.Sprintf("%s / %s", formatBytes(result.BlockRead), formatBytes(result.BlockWrite)),
+			fmt.Sprintf("%d", result.Pids),
+			formatPressure(result.Pressure),
+		})
+	}
+	table.Render()
+}
+
+// formatPressure formats the some avg10 of cpu, memory and io, "-" if PSI is not available
+func formatPressure(pressure *cgroups.Pressure) string {
+	if pressure == nil {
+		return "-"
+	}
+	avg10 := make([]string, 0, 3)
+	for _, psi := range []*cgroups.PSIStats{pressure.Cpu, pressure.Memory, pressure.Io} {
+		avg10 = append(avg10, fmt.Sprintf("%.2f%%", psi.Some.Avg10))
+	}
+	return strings.Join(avg10, " / ")
+}
+
+// formatBytes formats the size with binary units, e.g. 1.5MiB
+func formatBytes(size uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d%s", size, units[i])
+	}
+	return fmt.Sprintf("%.2f%s", value, units[i])
+}
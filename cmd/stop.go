@@ -2,16 +2,18 @@ package cmd
 
 import (
 	"MyDocker/container"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"path"
 	"strconv"
 	"syscall"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+// stop 等待容器退出的默认秒数，超时后发送 SIGKILL
+const defaultStopTimeout = 10
+
 var stopCmd = &cobra.Command{
 	Use:   "stop NAME",
 	Short: "Stop a container",
@@ -22,59 +24,72 @@ var stopCmd = &cobra.Command{
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
 		containerName := args[0]
-		return stopContainer(containerName)
+		timeout, _ := cmd.Flags().GetInt("time")
+		return stopContainer(containerName, time.Duration(timeout)*time.Second)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(stopCmd)
+	stopCmd.Flags().IntP("time", "t", defaultStopTimeout, "Seconds to wait for stop before killing it")
 }
 
-func getContainInfoByName(containerName string) (*container.ContainerInfo, error) {
-	containerDir := fmt.Sprintf(container.DefaultInfoLocation, containerName)
-	configFilePath := path.Join(containerDir, container.ConfigName)
-	content, err := ioutil.ReadFile(configFilePath)
+// stopContainer sends the stop signal of the container and waits for it to exit, the
+// container is killed if it is still running after timeout
+func stopContainer(containerName string, timeout time.Duration) error {
+	containerInfo, err := container.GetContainerInfo(containerName)
 	if err != nil {
-		return nil, fmt.Errorf("read file %s failed: %v", configFilePath, err)
+		return fmt.Errorf("get container info failed: %v", err)
 	}
-	var containerInfo container.ContainerInfo
-	if err := json.Unmarshal(content, &containerInfo); err != nil {
-		return nil, fmt.Errorf("unmarshal failed: %v", err)
+	if containerInfo.Status != container.RUNNING && containerInfo.Status != container.PAUSED {
+		return fmt.Errorf("container %s is not running", containerName)
 	}
-	return &containerInfo, nil
-}
 
-func stopContainer(containerName string) error {
-	pid, err := getContainerPidByName(containerName)
+	stopSignal := containerInfo.StopSignal
+	if stopSignal == "" {
+		stopSignal = container.DefaultStopSignal
+	}
+	sig, err := container.ParseSignal(stopSignal)
 	if err != nil {
-		return fmt.Errorf("get container pid failed: %v", err)
+		return err
 	}
-
-	pidInt, _ := strconv.Atoi(pid)
-	if err := syscall.Kill(pidInt, syscall.SIGTERM); err != nil {
+	// 被冻结的进程恢复后才能处理信号
+	if containerInfo.Status == container.PAUSED {
+		if err := unpauseContainer(containerName); err != nil {
+			return err
+		}
+	}
+	pid, _ := strconv.Atoi(containerInfo.PID)
+	if err := signalContainer(pid, sig); err != nil {
 		return fmt.Errorf("stop container failed: %v", err)
 	}
 
-	containerInfo, err := getContainInfoByName(containerName)
-	if err != nil {
-		return fmt.Errorf("get container info failed: %v", err)
+	// monitor 释放锁时容器进程已经退出且资源已经释放
+	if err := container.WaitContainerMonitor(containerName, timeout); err != nil {
+		log.Warnf("container %s did not exit in %v, killing it", containerName, timeout)
+		if err := signalContainer(pid, syscall.SIGKILL); err != nil {
+			return fmt.Errorf("kill container failed: %v", err)
+		}
+		if err := container.WaitContainerMonitor(containerName, monitorWaitTimeout); err != nil {
+			return err
+		}
 	}
 
-	// modify container status
-	containerInfo.Status = container.STOP
-	containerInfo.PID = " "
-
-	marshalContent, err := json.Marshal(containerInfo)
-	if err != nil {
-		return fmt.Errorf("marshal container info failed: %v", err)
-	}
+	// 确认进程退出后再修改状态，退出码等信息已由 monitor 记录
+	_, err = container.ModifyContainerInfo(containerName, func(info *container.ContainerInfo) error {
+		info.Status = container.STOP
+		return nil
+	})
+	return err
+}
 
-	containerDir := fmt.Sprintf(container.DefaultInfoLocation, containerName)
-	configFilePath := path.Join(containerDir, container.ConfigName)
-	// overwrite
-	if err := ioutil.WriteFile(configFilePath, marshalContent, 0622); err != nil {
-		return fmt.Errorf("write config file failed: %v", err)
+// signalContainer sends a signal to the container process, a process that has
+// already exited is not an error
+func signalContainer(pid int, sig syscall.Signal) error {
+	if err := syscall.Kill(pid, sig); err != nil && err != syscall.ESRCH {
+		return err
 	}
 	return nil
 }
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/cgroups/subsystems"
+	"MyDocker/container"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(updateCmd)
+	addMemoryFlags(updateCmd)
+	updateCmd.Flags().String("cpushare", "", "CPUshare limit")
+	addCpusetFlags(updateCmd)
+	updateCmd.Flags().Int64("pids-limit", 0, "Tune container pids limit (set -1 for unlimited)")
+	addCpuQuotaFlags(updateCmd)
+}
+
+var updateCmd = &cobra.Command{
+	Use:   "update [OPTIONS] NAME",
+	Short: "Update resource limits of a container",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 {
+			return fmt.Errorf("missing container name")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		containerInfo, err := container.GetContainerInfo(args[0])
+		if err != nil {
+			return fmt.Errorf("get container info failed: %v", err)
+		}
+		// 之前版本创建的容器信息中没有 resource
+		if containerInfo.Resource == nil {
+			containerInfo.Resource = &subsystems.ResourceConfig{}
+		}
+		// 只修改命令行中指定的限制
+		res := containerInfo.Resource
+		if cmd.Flags().Changed("cpushare") {
+			res.CpuShare, _ = cmd.Flags().GetString("cpushare")
+		}
+		if cmd.Flags().Changed("pids-limit") {
+			res.PidsLimit, _ = cmd.Flags().GetInt64("pids-limit")
+		}
+		if err := parseMemoryFlags(cmd, res); err != nil {
+			return err
+		}
+		if res.CpusExclusive > 0 && (cmd.Flags().Changed("cpuset") || cmd.Flags().Changed("cpuset-cpus")) {
+			return fmt.Errorf("cannot change the cpus of container %s which has exclusive cpus", containerInfo.Name)
+		}
+		if err := parseCpusetFlags(cmd, res); err != nil {
+			return err
+		}
+		if err := parseCpuQuotaFlags(cmd, res); err != nil {
+			return err
+		}
+		if err := res.Validate(); err != nil {
+			return err
+		}
+		return updateContainer(containerInfo)
+	},
+}
+
+// updateContainer writes the resource limits of the container into its cgroup and
+// persists them, a container which is not running gets them on the next start
+func updateContainer(containerInfo *container.ContainerInfo) error {
+	if containerInfo.Status == container.RUNNING || containerInfo.Status == container.PAUSED {
+		res, err := containerResource(containerInfo)
+		if err != nil {
+			return err
+		}
+		cgroupManager := cgroups.NewCgroupManager(containerInfo.CgroupPath())
+		if err := cgroupManager.Set(res); err != nil {
+			return fmt.Errorf("update container resource failed: %v", err)
+		}
+	}
+	_, err := container.ModifyContainerInfo(containerInfo.Name, func(info *container.ContainerInfo) error {
+		info.Resource = containerInfo.Resource
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,101 @@
+package container
+
+import (
+	"MyDocker/cgroups"
+	"MyDocker/cgroups/subsystems"
+	"fmt"
+	"path"
+	"reflect"
+)
+
+// 所有容器共用的 cgroup parent 记录，同一个 cgroup parent 的总体限制只保存一份
+const cgroupParentLedgerName = "cgroup-parents.json"
+
+type cgroupParentLedger struct {
+	Resources map[string]*subsystems.ResourceConfig `json:"resources"` // key 为 cgroup parent
+	Created   map[string]bool                       `json:"created"`   // 由 mydocker 创建的 cgroup，只有这些 cgroup 会被删除
+}
+
+func withCgroupParentLedger(write bool, fn func(ledger *cgroupParentLedger) error) error {
+	ledger := &cgroupParentLedger{
+		Resources: make(map[string]*subsystems.ResourceConfig),
+		Created:   make(map[string]bool),
+	}
+	return withLedger(cgroupParentLedgerName, write, ledger, func() error {
+		return fn(ledger)
+	})
+}
+
+// SetCgroupParent records the cgroups on the way to the parent which do not exist yet and
+// sets the limits of the parent. The limits are stored once for each parent, res may be
+// nil to use the stored ones, and different limits are rejected
+func SetCgroupParent(parent string, res *subsystems.ResourceConfig) error {
+	var setErr error
+	err := withCgroupParentLedger(true, func(ledger *cgroupParentLedger) error {
+		stored := ledger.Resources[parent]
+		if res != nil && stored != nil && !reflect.DeepEqual(res, stored) {
+			return fmt.Errorf("cgroup parent %s already has different limits, which are shared by all containers in it", parent)
+		}
+		for p := parent; p != "/"; p = path.Dir(p) {
+			if !cgroups.ParentExists(p) {
+				ledger.Created[p] = true
+			}
+		}
+		if stored == nil {
+			if res == nil {
+				// 没有限制的 cgroup parent 在创建容器的 cgroup 时一起创建
+				return nil
+			}
+			ledger.Resources[parent] = res
+			stored = res
+		}
+		// 即使设置失败也要保存创建的 cgroup，回滚时才能删除
+		setErr = cgroups.SetParent(parent, stored)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return setErr
+}
+
+// RemoveCgroupParent removes the cgroups created by mydocker from the parent upwards,
+// others are the cgroup parents of the other containers, which are kept with their
+// ancestors
+func RemoveCgroupParent(parent string, others []string) error {
+	return withCgroupParentLedger(true, func(ledger *cgroupParentLedger) error {
+		inUse := parentsInUse(others)
+		if inUse[parent] {
+			return nil
+		}
+		delete(ledger.Resources, parent)
+		for _, p := range cgroups.RemoveParent(removableParents(parent, ledger.Created, inUse)) {
+			delete(ledger.Created, p)
+		}
+		return nil
+	})
+}
+
+// parentsInUse returns the cgroup parents and all their ancestors
+func parentsInUse(parents []string) map[string]bool {
+	inUse := make(map[string]bool)
+	for _, parent := range parents {
+		for p := parent; p != "/"; p = path.Dir(p) {
+			inUse[p] = true
+		}
+	}
+	return inUse
+}
+
+// removableParents returns the cgroups from the parent upwards which are created by
+// mydocker and are not in use
+func removableParents(parent string, created map[string]bool, inUse map[string]bool) []string {
+	var parents []string
+	for p := parent; p != "/"; p = path.Dir(p) {
+		if inUse[p] || !created[p] {
+			break
+		}
+		parents = append(parents, p)
+	}
+	return parents
+}
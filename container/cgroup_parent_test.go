@@ -0,0 +1,29 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemovableParents(t *testing.T) {
+	created := map[string]bool{"/mydocker": true, "/mydocker/a": true, "/mydocker/a/b": true, "/mydocker/ab": true}
+	for _, c := range []struct {
+		parent   string
+		others   []string
+		expected []string
+	}{
+		{"/mydocker/a/b", nil, []string{"/mydocker/a/b", "/mydocker/a", "/mydocker"}},
+		// 前缀相同但不是子 cgroup 的 parent 不影响删除
+		{"/mydocker/a", []string{"/mydocker/ab"}, []string{"/mydocker/a"}},
+		{"/mydocker/a/b", []string{"/mydocker/a"}, []string{"/mydocker/a/b"}},
+		{"/mydocker/a", []string{"/mydocker/a/b/c"}, nil},
+		// 不是 mydocker 创建的 cgroup 不删除
+		{"/system.slice/mydocker", nil, nil},
+		{"/mydocker/c", nil, nil},
+	} {
+		parents := removableParents(c.parent, created, parentsInUse(c.others))
+		if !reflect.DeepEqual(parents, c.expected) {
+			t.Errorf("removable parents of %s with %v are %v, expect %v", c.parent, c.others, parents, c.expected)
+		}
+	}
+}
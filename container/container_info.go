@@ -1,14 +1,16 @@
 package container
 
 import (
+	"MyDocker/cgroups"
+	"MyDocker/cgroups/subsystems"
 	"MyDocker/reexec"
 	"MyDocker/util"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path"
-	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,64 +19,161 @@ import (
 )
 
 var (
+	CREATED             string = "created"
 	RUNNING             string = "running"
+	PAUSED              string = "paused"
 	STOP                string = "stopped"
 	EXIT                string = "exited"
 	DefaultInfoLocation string = "/var/run/mydocker/%s/"
 	ConfigName          string = "config.json"
+	configLockName      string = "config.lock"
 	ContainerLogFile    string = "container.log"
+	timeLayout          string = "2006-01-02 15:04:05"
 )
 
 type ContainerInfo struct {
-	PID         string   `json:"pid"`         // 容器进程在 host 上的 pid
-	ID          string   `json:"id"`          // 容器 ID
-	Name        string   `json:"name"`        // 容器名
-	Command     string   `json:"command"`     // 容器进程运行的命令
-	CreatedTime string   `json:"createdTime"` // 创建时间
-	Status      string   `json:"status"`      // 容器状态
-	Volume      string   `json:"volume"`      // 数据卷
-	PortMapping []string `json:"portmapping"` // 端口映射
+	PID            string                     `json:"pid"`            // 容器进程在 host 上的 pid
+	ID             string                     `json:"id"`             // 容器 ID
+	Name           string                     `json:"name"`           // 容器名
+	Command        string                     `json:"command"`        // 容器进程运行的命令
+	CreatedTime    string                     `json:"createdTime"`    // 创建时间
+	Status         string                     `json:"status"`         // 容器状态
+	Volume         string                     `json:"volume"`         // 数据卷
+	PortMapping    []string                   `json:"portmapping"`    // 端口映射
+	IPAddress      string                     `json:"ip"`             // 容器网络 IP
+	ExitCode       int                        `json:"exitCode"`       // 容器进程退出码
+	FinishedTime   string                     `json:"finishedTime"`   // 退出时间
+	OOMKilled      bool                       `json:"oomKilled"`      // 本次运行中是否有进程被 OOM killer 杀死
+	OOMKillCount   uint64                     `json:"oomKillCount"`   // 本次运行中被 OOM killer 杀死的进程数
+	Image          string                     `json:"image"`          // 镜像名
+	Network        string                     `json:"network"`        // 容器网络
+	Tty            bool                       `json:"tty"`            // 是否在前台运行
+	StopSignal     string                     `json:"stopSignal"`     // stop 时发送给容器进程的信号
+	Spec           *InitSpec                  `json:"spec"`           // 容器 init 进程的启动配置
+	Resource       *subsystems.ResourceConfig `json:"resource"`       // 资源限制
+	PressureAlerts []cgroups.PressureAlert    `json:"pressureAlerts"` // 资源 stall 时间的告警阈值
+	CgroupParent   string                     `json:"cgroupParent"`   // 容器 cgroup 的父 cgroup，为空时位于根 cgroup 下
+	ParentResource *subsystems.ResourceConfig `json:"parentResource"` // cgroup parent 中所有容器的总体限制
 }
 
-func RecordContainerInfo(containerPID int, containerName string, containerID string, volume string, cmdArray []string) error {
+// CgroupPath returns the path of the container cgroup relative to the cgroup root
+func (c *ContainerInfo) CgroupPath() string {
+	return path.Join(c.CgroupParent, c.ID)
+}
+
+// RecordContainerInfo records a newly created container
+func RecordContainerInfo(containerInfo *ContainerInfo) error {
+	containerInfo.CreatedTime = time.Now().Format(timeLayout)
+	containerInfo.Command = strings.Join(containerInfo.Spec.Args, " ")
+	containerInfo.Status = CREATED
+
+	// rw-r-r
+	if err := os.MkdirAll(getContainerInfoDir(containerInfo.Name), 0666); err != nil {
+		return fmt.Errorf("record container info: %v", err)
+	}
+	lock, err := lockContainerInfo(containerInfo.Name)
+	if err != nil {
+		return fmt.Errorf("record container info: %v", err)
+	}
+	defer lock.Close()
+	if err := writeContainerInfo(containerInfo); err != nil {
+		return fmt.Errorf("record container info: %v", err)
+	}
+	return nil
+}
+
+// GetContainerInfo reads the config of the container from its info dir
+func GetContainerInfo(containerName string) (*ContainerInfo, error) {
+	configFilePath := path.Join(getContainerInfoDir(containerName), ConfigName)
+	content, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("read file %s failed: %v", configFilePath, err)
+	}
+	var containerInfo ContainerInfo
+	if err := json.Unmarshal(content, &containerInfo); err != nil {
+		return nil, fmt.Errorf("unmarshal container %s info failed: %v", containerName, err)
+	}
+	return &containerInfo, nil
+}
+
+// ModifyContainerInfo reads the latest config of the container, lets modify change it
+// and writes it back. The config lock is held meanwhile so that concurrent commands and
+// the monitor do not lose each other's changes.
+func ModifyContainerInfo(containerName string, modify func(containerInfo *ContainerInfo) error) (*ContainerInfo, error) {
+	lock, err := lockContainerInfo(containerName)
+	if err != nil {
+		return nil, fmt.Errorf("update container info: %v", err)
+	}
+	defer lock.Close()
+	containerInfo, err := GetContainerInfo(containerName)
+	if err != nil {
+		return nil, err
+	}
+	if err := modify(containerInfo); err != nil {
+		return nil, err
+	}
+	if err := writeContainerInfo(containerInfo); err != nil {
+		return nil, fmt.Errorf("update container info: %v", err)
+	}
+	return containerInfo, nil
+}
 
-	createdTime := time.Now().Format("2006-01-02 15:04:59")
-	command := strings.Join(cmdArray, " ")
+// RecordContainerExit marks the container as exited with the given exit code
+func RecordContainerExit(containerName string, exitCode int) error {
+	_, err := ModifyContainerInfo(containerName, func(containerInfo *ContainerInfo) error {
+		containerInfo.Status = EXIT
+		containerInfo.PID = " "
+		containerInfo.ExitCode = exitCode
+		containerInfo.FinishedTime = time.Now().Format(timeLayout)
+		return nil
+	})
+	return err
+}
 
-	containerInfo := &ContainerInfo{
-		PID:         strconv.Itoa(containerPID),
-		ID:          containerID,
-		Command:     command,
-		CreatedTime: createdTime,
-		Status:      RUNNING,
-		Name:        containerName,
-		Volume:      volume,
+// lockContainerInfo takes the config lock of the container, it is released when the
+// returned file is closed
+func lockContainerInfo(containerName string) (*os.File, error) {
+	// 不创建 info 目录，已经删除的容器不会因为修改而重新出现
+	lockFile, err := os.OpenFile(path.Join(getContainerInfoDir(containerName), configLockName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open config lock failed: %v", err)
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX); err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("lock config failed: %v", err)
 	}
+	return lockFile, nil
+}
 
+// writeContainerInfo replaces config.json atomically, readers never see a partially
+// written file
+func writeContainerInfo(containerInfo *ContainerInfo) error {
 	jsonBytes, err := json.Marshal(containerInfo)
 	if err != nil {
-		return fmt.Errorf("record container info: %v", err)
+		return err
 	}
-	infoStorageDir := getContainerInfoDir(containerName)
+	infoStorageDir := getContainerInfoDir(containerInfo.Name)
 	if !util.PathExists(infoStorageDir) {
 		// rw-r-r
 		if err := os.MkdirAll(infoStorageDir, 0666); err != nil {
-			return fmt.Errorf("record container info: %v", err)
+			return err
 		}
 	}
 
-	configFileName := path.Join(infoStorageDir, ConfigName)
-	configFile, err := os.Create(configFileName)
+	// 先写入同一目录下的临时文件，再通过 rename 替换 config.json
+	configFile, err := ioutil.TempFile(infoStorageDir, ConfigName+".tmp")
 	if err != nil {
-		return fmt.Errorf("record container info: %v", err)
+		return err
 	}
-	defer configFile.Close()
-	// write json to file
-	if _, err := configFile.WriteString(string(jsonBytes)); err != nil {
-		return fmt.Errorf("record container info: %v", err)
+	defer os.Remove(configFile.Name())
+	if _, err := configFile.Write(jsonBytes); err != nil {
+		configFile.Close()
+		return err
 	}
-
-	return nil
+	if err := configFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(configFile.Name(), path.Join(infoStorageDir, ConfigName))
 }
 
 func DeleteContainerInfo(containerName string) error {
@@ -85,30 +184,18 @@ func DeleteContainerInfo(containerName string) error {
 	return nil
 }
 
-// NewContainerProcess creates a container process
-func NewContainerProcess(tty bool, containerName string, volume string, imageName string, envSlice []string) (*exec.Cmd, *os.File, error) {
+// NewContainerProcess creates a container process, it returns the command, the pipe to
+// send init spec and the pipe to receive sync messages from the init process
+func NewContainerProcess(tty bool, containerName string) (*exec.Cmd, *os.File, *os.File, error) {
 	readPipe, writePipe, err := os.Pipe()
 	if err != nil {
-		return nil, nil, fmt.Errorf("new pipe failed: %v", err)
+		return nil, nil, nil, fmt.Errorf("new pipe failed: %v", err)
 	}
-	cmd := reexec.Command("containerInitProcess")
-	cmd.SysProcAttr = &syscall.SysProcAttr{
-		Cloneflags: syscall.CLONE_NEWUTS | syscall.CLONE_NEWIPC | syscall.CLONE_NEWPID | syscall.CLONE_NEWNS | syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
-		UidMappings: []syscall.SysProcIDMap{
-			{
-				ContainerID: 0,
-				HostID:      syscall.Getuid(),
-				Size:        1,
-			},
-		},
-		GidMappings: []syscall.SysProcIDMap{
-			{
-				ContainerID: 0,
-				HostID:      syscall.Getgid(),
-				Size:        1,
-			},
-		},
+	syncReadPipe, syncWritePipe, err := os.Pipe()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("new sync pipe failed: %v", err)
 	}
+	cmd := NewInitCommand()
 
 	if tty {
 		cmd.Stdin = os.Stdin
@@ -122,20 +209,33 @@ func NewContainerProcess(tty bool, containerName string, volume string, imageNam
 			}
 		}
 		logFilePath := path.Join(containerInfoDir, ContainerLogFile)
-		logFileFd, err := os.Create(logFilePath)
+		// 重启的容器继续追加日志
+		logFileFd, err := os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		cmd.Stdout = logFileFd
 		cmd.Stderr = logFileFd
 	}
-	cmd.ExtraFiles = []*os.File{readPipe}
-	cmd.Env = append(os.Environ(), envSlice...)
-	if err := NewWorkSpace(volume, imageName, containerName); err != nil {
-		return nil, nil, err
-	}
+	// 依次对应 init 进程的 fd 3 和 fd 4
+	cmd.ExtraFiles = []*os.File{readPipe, syncWritePipe}
+	cmd.Env = os.Environ()
 	cmd.Dir = fmt.Sprintf(MntURL, containerName)
-	return cmd, writePipe, nil
+	return cmd, writePipe, syncReadPipe, nil
+}
+
+// NewInitCommand returns the command which starts the container init process in new namespaces
+func NewInitCommand() *exec.Cmd {
+	cmd := reexec.Command("containerInitProcess")
+	uidMappings, gidMappings := IDMappings()
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUTS | syscall.CLONE_NEWIPC | syscall.CLONE_NEWPID | syscall.CLONE_NEWNS | syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+		UidMappings: uidMappings,
+		GidMappings: gidMappings,
+		// 允许容器 init 进程切换用户时调用 setgroups
+		GidMappingsEnableSetgroups: true,
+	}
+	return cmd
 }
 
 // getContainerInfoDir returns url where container infomation is stored
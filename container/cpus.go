@@ -0,0 +1,91 @@
+package container
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 所有容器共用的独占 CPU 分配记录，key 为 CPU 编号，value 为独占该 CPU 的容器名
+const cpuLedgerName = "cpus.json"
+
+// withCpuLedger locks the ledger and calls fn with its content, the ledger is written
+// back if write is set and fn succeeds
+func withCpuLedger(write bool, fn func(ledger map[int]string) error) error {
+	ledger := make(map[int]string)
+	return withLedger(cpuLedgerName, write, &ledger, func() error {
+		return fn(ledger)
+	})
+}
+
+// AllocateExclusiveCpus records n cpus of hostCpus which are not allocated yet as
+// exclusively used by the container, the cpus returned by pinnedCpus are not allocated.
+// pinnedCpus is called with the ledger locked
+func AllocateExclusiveCpus(containerName string, n int, hostCpus []int, pinnedCpus func() (map[int]bool, error)) ([]int, error) {
+	var cpus []int
+	err := withCpuLedger(true, func(ledger map[int]string) error {
+		pinned, err := pinnedCpus()
+		if err != nil {
+			return err
+		}
+		if cpus, err = allocateCpus(ledger, hostCpus, pinned, n); err != nil {
+			return err
+		}
+		for _, cpu := range cpus {
+			ledger[cpu] = containerName
+		}
+		return nil
+	})
+	return cpus, err
+}
+
+// allocateCpus picks n free cpus which are not pinned, at least one cpu is kept for
+// containers without exclusive cpus
+func allocateCpus(ledger map[int]string, hostCpus []int, pinned map[int]bool, n int) ([]int, error) {
+	var free []int
+	shared := 0
+	for _, cpu := range hostCpus {
+		if _, ok := ledger[cpu]; ok {
+			continue
+		}
+		shared++
+		// 指定了 cpuset 的容器仍在使用的 CPU 不能独占
+		if !pinned[cpu] {
+			free = append(free, cpu)
+		}
+	}
+	sort.Ints(free)
+	available := len(free)
+	if available > shared-1 {
+		available = shared - 1
+	}
+	if n > available {
+		if available < 0 {
+			available = 0
+		}
+		return nil, fmt.Errorf("not enough free cpus: %d requested, %d available", n, available)
+	}
+	// 从编号大的 CPU 开始分配，CPU 0 通常承担更多的中断处理
+	return free[len(free)-n:], nil
+}
+
+// ReleaseExclusiveCpus removes cpus allocated to the container from the ledger
+func ReleaseExclusiveCpus(containerName string) error {
+	return withCpuLedger(true, func(ledger map[int]string) error {
+		for cpu, owner := range ledger {
+			if owner == containerName {
+				delete(ledger, cpu)
+			}
+		}
+		return nil
+	})
+}
+
+// ExclusiveCpus returns the allocated cpus and their containers
+func ExclusiveCpus() (map[int]string, error) {
+	var result map[int]string
+	err := withCpuLedger(false, func(ledger map[int]string) error {
+		result = ledger
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,31 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllocateCpus(t *testing.T) {
+	ledger := map[int]string{3: "c1"}
+	cpus, err := allocateCpus(ledger, []int{0, 1, 2, 3}, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cpus, []int{1, 2}) {
+		t.Errorf("unexpected cpus %v", cpus)
+	}
+	// 至少保留一个没有被独占的 CPU 给共享的容器
+	if _, err := allocateCpus(ledger, []int{0, 1, 2, 3}, nil, 3); err == nil {
+		t.Error("expect error when no cpu is left for shared containers")
+	}
+	// 被其他容器指定使用的 CPU 跳过
+	if cpus, err = allocateCpus(ledger, []int{0, 1, 2, 3}, map[int]bool{2: true}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cpus, []int{0, 1}) {
+		t.Errorf("unexpected cpus %v", cpus)
+	}
+	if _, err := allocateCpus(ledger, []int{0, 1, 2, 3}, map[int]bool{1: true, 2: true}, 2); err == nil {
+		t.Error("expect error when the free cpus are pinned")
+	}
+}
@@ -0,0 +1,102 @@
+package container
+
+import (
+	"MyDocker/cgroups/subsystems"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Device 是在容器 /dev 中创建的设备文件
+type Device struct {
+	Path     string `json:"path"`     // 容器内的路径
+	HostPath string `json:"hostPath"` // host 上对应的设备文件
+}
+
+// 每个容器都会创建的设备文件，对应 subsystems.DefaultDeviceRules
+var defaultDevicePaths = []string{"/dev/null", "/dev/zero", "/dev/full", "/dev/random", "/dev/urandom", "/dev/tty"}
+
+// DefaultDevices returns device files every container gets
+func DefaultDevices() []Device {
+	devices := make([]Device, 0, len(defaultDevicePaths))
+	for _, devicePath := range defaultDevicePaths {
+		devices = append(devices, Device{Path: devicePath, HostPath: devicePath})
+	}
+	return devices
+}
+
+// ParseDevice parses a device in the form of host-path[:container-path][:permissions],
+// it returns the device file to create and the rule to allow in the devices cgroup
+func ParseDevice(spec string) (*Device, *subsystems.DeviceRule, error) {
+	parts := strings.Split(spec, ":")
+	device := &Device{HostPath: parts[0]}
+	permissions := "rwm"
+	switch len(parts) {
+	case 1:
+		device.Path = parts[0]
+	case 2:
+		// 第二部分可能是权限，也可能是容器内的路径
+		if isDevicePermissions(parts[1]) {
+			device.Path = parts[0]
+			permissions = parts[1]
+		} else {
+			device.Path = parts[1]
+		}
+	case 3:
+		device.Path = parts[1]
+		permissions = parts[2]
+	default:
+		return nil, nil, fmt.Errorf("invalid device %q, should be host-path[:container-path][:permissions]", spec)
+	}
+	if !filepath.IsAbs(device.HostPath) || !filepath.IsAbs(device.Path) {
+		return nil, nil, fmt.Errorf("invalid device %q: path must be absolute", spec)
+	}
+	if !isDevicePermissions(permissions) {
+		return nil, nil, fmt.Errorf("invalid device %q: permissions must be a combination of r, w and m", spec)
+	}
+	devType, major, minor, err := subsystems.StatDevice(device.HostPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	rule := &subsystems.DeviceRule{
+		Type:        string(devType),
+		Major:       major,
+		Minor:       minor,
+		Permissions: permissions,
+	}
+	return device, rule, nil
+}
+
+func isDevicePermissions(permissions string) bool {
+	if permissions == "" || len(permissions) > 3 {
+		return false
+	}
+	for i, perm := range permissions {
+		if !strings.ContainsRune("rwm", perm) || strings.ContainsRune(permissions[i+1:], perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// setUpDevices creates the device files in the rootfs, user namespace 中不允许 mknod，
+// 所以将 host 的设备文件 bind mount 到容器中
+func setUpDevices(rootfs string, devices []Device) error {
+	for _, device := range devices {
+		target := filepath.Join(rootfs, device.Path)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("mkdir for device %s error %v", device.Path, err)
+		}
+		f, err := os.OpenFile(target, os.O_CREATE, 0666)
+		if err != nil {
+			return fmt.Errorf("create device %s error %v", device.Path, err)
+		}
+		f.Close()
+		if err := syscall.Mount(device.HostPath, target, "bind", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("bind mount device %s error %v", device.Path, err)
+		}
+	}
+	return nil
+}
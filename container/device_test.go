@@ -0,0 +1,33 @@
+package container
+
+import "testing"
+
+func TestParseDevice(t *testing.T) {
+	cases := []struct {
+		spec        string
+		path        string
+		permissions string
+	}{
+		{"/dev/null", "/dev/null", "rwm"},
+		{"/dev/null:r", "/dev/null", "r"},
+		{"/dev/null:/dev/xnull", "/dev/xnull", "rwm"},
+		{"/dev/null:/dev/xnull:rw", "/dev/xnull", "rw"},
+	}
+	for _, c := range cases {
+		device, rule, err := ParseDevice(c.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if device.HostPath != "/dev/null" || device.Path != c.path {
+			t.Errorf("device %q parsed as %+v", c.spec, device)
+		}
+		if got := rule.String(); got != "c 1:3 "+c.permissions {
+			t.Errorf("device %q has rule %q", c.spec, got)
+		}
+	}
+	for _, invalid := range []string{"null", "/dev/null:rr", "/dev/null:/dev/x:rwx", "/dev/null:a:b:c", "/dev/nonexistent"} {
+		if _, _, err := ParseDevice(invalid); err == nil {
+			t.Errorf("expect error for device %q", invalid)
+		}
+	}
+}
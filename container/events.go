@@ -0,0 +1,129 @@
+package container
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"time"
+)
+
+// 所有容器的事件按行追加到该文件中，超过 maxEventsLogSize 时轮转为 events.log.1，只保留一份旧的日志
+const (
+	eventsLogName    = "events.log"
+	maxEventsLogSize = 1 << 20
+)
+
+// 容器事件类型
+const (
+	EventOOM      = "oom"      // 容器的 cgroup 发生了 OOM
+	EventPressure = "pressure" // 资源的 stall 时间超过了告警阈值
+)
+
+// Event 是容器运行过程中发生的事件
+type Event struct {
+	Time       string            `json:"time"`
+	Type       string            `json:"type"`
+	ID         string            `json:"id"`   // 容器 ID
+	Name       string            `json:"name"` // 容器名
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func getEventsLogPath() string {
+	return path.Join(path.Dir(fmt.Sprintf(DefaultInfoLocation, "")), eventsLogName)
+}
+
+// EmitEvent appends an event of the container to the events log
+func EmitEvent(containerInfo *ContainerInfo, eventType string, attributes map[string]string) error {
+	content, err := json.Marshal(&Event{
+		Time:       time.Now().Format(timeLayout),
+		Type:       eventType,
+		ID:         containerInfo.ID,
+		Name:       containerInfo.Name,
+		Attributes: attributes,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal event failed: %v", err)
+	}
+	logFile, err := openEventsLog()
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	if _, err := logFile.Write(append(content, '\n')); err != nil {
+		return fmt.Errorf("write event failed: %v", err)
+	}
+	return nil
+}
+
+// openEventsLog opens the events log for appending with it locked, the log is rotated
+// first if it exceeds maxEventsLogSize
+func openEventsLog() (*os.File, error) {
+	logPath := getEventsLogPath()
+	for {
+		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("open events log failed: %v", err)
+		}
+		if err := syscall.Flock(int(logFile.Fd()), syscall.LOCK_EX); err != nil {
+			logFile.Close()
+			return nil, fmt.Errorf("lock events log failed: %v", err)
+		}
+		info, err := logFile.Stat()
+		if err != nil {
+			logFile.Close()
+			return nil, fmt.Errorf("stat events log failed: %v", err)
+		}
+		// 等待锁的过程中日志可能已经被其他进程轮转，需要重新打开
+		if current, err := os.Stat(logPath); err != nil || !os.SameFile(info, current) {
+			logFile.Close()
+			continue
+		}
+		if info.Size() < maxEventsLogSize {
+			return logFile, nil
+		}
+		err = os.Rename(logPath, logPath+".1")
+		logFile.Close()
+		if err != nil {
+			return nil, fmt.Errorf("rotate events log failed: %v", err)
+		}
+	}
+}
+
+// ReadEvents returns all recorded events in the order they happened
+func ReadEvents() ([]*Event, error) {
+	logPath := getEventsLogPath()
+	events, err := readEventsLog(logPath + ".1")
+	if err != nil {
+		return nil, err
+	}
+	current, err := readEventsLog(logPath)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, current...), nil
+}
+
+func readEventsLog(logPath string) ([]*Event, error) {
+	logFile, err := os.Open(logPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open events log failed: %v", err)
+	}
+	defer logFile.Close()
+
+	var events []*Event
+	scanner := bufio.NewScanner(logFile)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("decode event failed: %v", err)
+		}
+		events = append(events, &event)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,70 @@
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// HOST_NAME_MAX，domainname 的长度限制相同
+const maxHostnameLen = 64
+
+// ValidateUTSNames checks the hostname and domainname are made of RFC 1123 labels
+// separated by dots, empty names are not checked
+func ValidateUTSNames(hostname string, domainname string) error {
+	if hostname != "" && !isValidHostname(hostname) {
+		return fmt.Errorf("invalid hostname %q", hostname)
+	}
+	if domainname != "" && !isValidHostname(domainname) {
+		return fmt.Errorf("invalid domainname %q", domainname)
+	}
+	return nil
+}
+
+func isValidHostname(name string) bool {
+	if len(name) > maxHostnameLen {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// DefaultHostname returns the container name if it can be used as the hostname,
+// otherwise the container ID
+func DefaultHostname(containerName string, containerID string) string {
+	if isValidHostname(containerName) {
+		return containerName
+	}
+	return containerID
+}
+
+// setHostname sets the hostname and domainname in the UTS namespace of the container
+// and writes the hostname to /etc/hostname
+func setHostname(hostname string, domainname string) error {
+	if err := syscall.Sethostname([]byte(hostname)); err != nil {
+		return fmt.Errorf("set hostname error: %v", err)
+	}
+	if domainname != "" {
+		if err := syscall.Setdomainname([]byte(domainname)); err != nil {
+			return fmt.Errorf("set domainname error: %v", err)
+		}
+	}
+	if err := os.MkdirAll("/etc", 0755); err != nil {
+		return fmt.Errorf("mkdir /etc error: %v", err)
+	}
+	if err := ioutil.WriteFile("/etc/hostname", []byte(hostname+"\n"), 0644); err != nil {
+		return fmt.Errorf("write /etc/hostname error: %v", err)
+	}
+	return nil
+}
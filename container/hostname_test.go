@@ -0,0 +1,20 @@
+package container
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateUTSNames(t *testing.T) {
+	if err := ValidateUTSNames("web-1", "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []string{"-web", "web_1", "a..b", strings.Repeat("a", 65)} {
+		if err := ValidateUTSNames(invalid, ""); err == nil {
+			t.Errorf("expect error for hostname %q", invalid)
+		}
+	}
+	if DefaultHostname("my_container", "abcdef1234") != "abcdef1234" {
+		t.Error("expect container ID as the hostname of an invalid container name")
+	}
+}
@@ -2,48 +2,139 @@ package container
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"syscall"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 )
 
 func RunContainerInitProcess() {
-	cmdArray := readUserCommand()
-	if cmdArray == nil || len(cmdArray) == 0 {
-		log.Error("container init failed: cmd is null")
-		return
+	sw := newSyncWriter(os.NewFile(uintptr(initSyncFd), "sync"))
+	if stage, err := initContainer(sw); err != nil {
+		log.Errorf("container init failed at stage %s: %v", stage, err)
+		sw.fail(stage, err)
+		os.Exit(1)
 	}
+}
+
+// initContainer sets up the container by the init spec and execs the user command,
+// it returns the stage which failed
+func initContainer(sw *syncWriter) (string, error) {
+	// exec 成功后 sync pipe 自动关闭，父进程据此判断容器启动成功
+	syscall.CloseOnExec(initSyncFd)
 
-	setUpMount()
-	path, err := exec.LookPath(cmdArray[0])
+	// 父进程将 init 进程加入容器的 cgroup 之后才发送 init spec，在此之前不能执行任何操作
+	specPipe := os.NewFile(uintptr(initSpecFd), "pipe")
+	spec, err := readInitSpec(specPipe)
+	specPipe.Close()
 	if err != nil {
-		log.Errorf("Exec path error: %v", err)
-		return
+		return StageSpec, err
 	}
-	if err := syscall.Exec(path, cmdArray, os.Environ()); err != nil {
-		log.Error(err.Error())
+	sw.ack(StageSpec)
+
+	// 此时创建的 cgroup namespace 以容器的 cgroup 为根。cgroup 和 time namespace 属于线程，
+	// 需要保证后续的挂载和 exec 在同一线程
+	runtime.LockOSThread()
+	if err := unix.Unshare(unix.CLONE_NEWCGROUP); err != nil {
+		return StageCgroupns, fmt.Errorf("unshare cgroup namespace error: %v", err)
 	}
-}
+	sw.ack(StageCgroupns)
+
+	if len(spec.TimeOffsets) > 0 {
+		if err := setUpTimeNamespace(spec.TimeOffsets); err != nil {
+			return StageTimens, err
+		}
+		sw.ack(StageTimens)
+	}
+
+	if err := setUpMount(spec.Mounts, spec.Devices); err != nil {
+		return StageMount, err
+	}
+	sw.ack(StageMount)
+
+	if spec.Hostname != "" {
+		if err := setHostname(spec.Hostname, spec.Domainname); err != nil {
+			return StageHostname, err
+		}
+		sw.ack(StageHostname)
+	}
+	if err := setRlimits(spec.Rlimits); err != nil {
+		return StageRlimit, err
+	}
+	sw.ack(StageRlimit)
 
-func readUserCommand() []string {
-	pipe := os.NewFile(uintptr(3), "pipe")
-	msg, err := ioutil.ReadAll(pipe)
+	if err := syscall.Chdir(spec.Cwd); err != nil {
+		return StageCwd, fmt.Errorf("chdir %s error: %v", spec.Cwd, err)
+	}
+	sw.ack(StageCwd)
+
+	if err := setUser(spec.User); err != nil {
+		return StageUser, err
+	}
+	sw.ack(StageUser)
+
+	// 使用容器的环境变量查找命令
+	os.Clearenv()
+	for _, env := range spec.Env {
+		if kv := strings.SplitN(env, "=", 2); len(kv) == 2 {
+			os.Setenv(kv[0], kv[1])
+		}
+	}
+	path, err := exec.LookPath(spec.Args[0])
 	if err != nil {
-		log.Errorf("init read pipe error %v", err)
+		return StageLookPath, fmt.Errorf("exec path error: %v", err)
+	}
+	sw.ack(StageLookPath)
+
+	if err := syscall.Exec(path, spec.Args, spec.Env); err != nil {
+		return StageExec, err
+	}
+	return "", nil
+}
+
+func setRlimits(rlimits []Rlimit) error {
+	for _, rlimit := range rlimits {
+		limit := &syscall.Rlimit{Cur: rlimit.Soft, Max: rlimit.Hard}
+		if err := syscall.Setrlimit(rlimitResources[rlimit.Type], limit); err != nil {
+			return fmt.Errorf("set rlimit %s error: %v", rlimit.Type, err)
+		}
+	}
+	return nil
+}
+
+func setUser(user string) error {
+	if user == "" {
 		return nil
 	}
-	msgStr := string(msg)
-	return strings.Split(msgStr, " ")
+	uid, gid, err := resolveUser(user)
+	if err != nil {
+		return err
+	}
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return fmt.Errorf("setgroups error: %v", err)
+	}
+	// 没有映射到 user namespace 中的 id 返回 EINVAL
+	if err := syscall.Setgid(gid); err == syscall.EINVAL {
+		return fmt.Errorf("setgid %d error: gid is not mapped in the user namespace", gid)
+	} else if err != nil {
+		return fmt.Errorf("setgid %d error: %v", gid, err)
+	}
+	if err := syscall.Setuid(uid); err == syscall.EINVAL {
+		return fmt.Errorf("setuid %d error: uid is not mapped in the user namespace", uid)
+	} else if err != nil {
+		return fmt.Errorf("setuid %d error: %v", uid, err)
+	}
+	return nil
 }
 
 func pivotRoot(root string) error {
 	if err := syscall.Mount(root, root, "bind", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
-		return fmt.Errorf("Mount rootfs to itself error")
+		return fmt.Errorf("mount rootfs to itself error %v", err)
 	}
 
 	pivotDir := filepath.Join(root, ".pivot_root")
@@ -68,23 +159,31 @@ func pivotRoot(root string) error {
 	return os.Remove(pivotDir)
 }
 
-func setUpMount() {
+func setUpMount(mounts []Mount, devices []Device) error {
 	pwd, err := os.Getwd()
 	if err != nil {
-		log.Errorf("Get current location error %v", err)
-		return
+		return fmt.Errorf("get current location error %v", err)
 	}
 
-	syscall.Mount("", "/", "", syscall.MS_PRIVATE|syscall.MS_REC, "")
-	pivotRoot(pwd)
-
-	// MS_NOEXEC 表示在本文件系统中不允许运行其他程序
-	// MS_NOSUID 表示在本系统中运行程序时，不允许 set-user-ID 或 set-group-ID
-	// MS_NODEV 是所有 mount 会设定的系统默认参数
-	defaultMountFlags := syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV
-	err = syscall.Mount("proc", "/proc", "proc", uintptr(defaultMountFlags), "")
-	if err != nil {
-		log.Errorf("mount /proc error %v", err)
+	if err := syscall.Mount("", "/", "", syscall.MS_PRIVATE|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("make / private error %v", err)
+	}
+	for _, m := range mounts {
+		// pivot_root 之前挂载，挂载点位于 rootfs 中
+		target := filepath.Join(pwd, m.Destination)
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("mkdir %s error %v", m.Destination, err)
+		}
+		if err := syscall.Mount(m.Source, target, m.Type, uintptr(m.Flags), m.Data); err != nil {
+			return fmt.Errorf("mount %s error %v", m.Destination, err)
+		}
+	}
+	// 设备文件位于 /dev 的 tmpfs 中，需要在挂载之后创建
+	if err := setUpDevices(pwd, devices); err != nil {
+		return err
+	}
+	if err := pivotRoot(pwd); err != nil {
+		return fmt.Errorf("pivot root error %v", err)
 	}
-	err = syscall.Mount("tmpfs", "/dev", "tmpfs", syscall.MS_NOSUID|syscall.MS_STRICTATIME, "mode=755")
+	return nil
 }
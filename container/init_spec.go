@@ -0,0 +1,251 @@
+package container
+
+import (
+	"MyDocker/cgroups/subsystems"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// InitSpecVersion is the version of InitSpec, the init process refuses specs of other versions
+const InitSpecVersion = 1
+
+// InitSpec 是父进程通过 pipe 发送给容器 init 进程的启动配置
+type InitSpec struct {
+	Version     int          `json:"version"`
+	Args        []string     `json:"args"`        // 容器进程的 argv
+	Env         []string     `json:"env"`         // 容器进程的环境变量
+	Cwd         string       `json:"cwd"`         // 容器进程的工作目录
+	Hostname    string       `json:"hostname"`    // 容器主机名
+	Domainname  string       `json:"domainname"`  // 容器的 NIS 域名
+	User        string       `json:"user"`        // 运行容器进程的用户, user[:group]
+	Mounts      []Mount      `json:"mounts"`      // pivot_root 前挂载到 rootfs 中的文件系统
+	Rlimits     []Rlimit     `json:"rlimits"`     // 容器进程的资源限制
+	Devices     []Device     `json:"devices"`     // 在容器 /dev 中创建的设备文件
+	TimeOffsets []TimeOffset `json:"timeOffsets"` // 容器 time namespace 中时钟的偏移，为空时不创建 time namespace
+}
+
+// Mount describes a mount applied inside the container rootfs
+type Mount struct {
+	Source      string `json:"source"`
+	Destination string `json:"destination"` // 容器内的挂载点
+	Type        string `json:"type"`
+	Flags       int    `json:"flags"`
+	Data        string `json:"data"`
+}
+
+// Rlimit describes a setrlimit(2) call
+type Rlimit struct {
+	Type string `json:"type"` // 资源名，例如 nofile
+	Soft uint64 `json:"soft"`
+	Hard uint64 `json:"hard"`
+}
+
+var rlimitResources = map[string]int{
+	"as":         unix.RLIMIT_AS,
+	"core":       unix.RLIMIT_CORE,
+	"cpu":        unix.RLIMIT_CPU,
+	"data":       unix.RLIMIT_DATA,
+	"fsize":      unix.RLIMIT_FSIZE,
+	"locks":      unix.RLIMIT_LOCKS,
+	"memlock":    unix.RLIMIT_MEMLOCK,
+	"msgqueue":   unix.RLIMIT_MSGQUEUE,
+	"nice":       unix.RLIMIT_NICE,
+	"nofile":     unix.RLIMIT_NOFILE,
+	"nproc":      unix.RLIMIT_NPROC,
+	"rss":        unix.RLIMIT_RSS,
+	"rtprio":     unix.RLIMIT_RTPRIO,
+	"rttime":     unix.RLIMIT_RTTIME,
+	"sigpending": unix.RLIMIT_SIGPENDING,
+	"stack":      unix.RLIMIT_STACK,
+}
+
+// NewInitSpec builds the init spec of a container process
+func NewInitSpec(cmdArray []string, envSlice []string, workdir string, user string, ulimits []string) (*InitSpec, error) {
+	if len(cmdArray) == 0 {
+		return nil, fmt.Errorf("missing container command")
+	}
+	rlimits, err := ParseRlimits(ulimits)
+	if err != nil {
+		return nil, err
+	}
+	if workdir == "" {
+		workdir = "/"
+	}
+	if err := checkUserMapped(user); err != nil {
+		return nil, err
+	}
+	return &InitSpec{
+		Version: InitSpecVersion,
+		Args:    cmdArray,
+		Env:     containerEnv(envSlice),
+		Cwd:     workdir,
+		User:    user,
+		Mounts:  DefaultMounts(),
+		Rlimits: rlimits,
+		Devices: DefaultDevices(),
+	}, nil
+}
+
+// 未通过 -e 指定 PATH 时容器进程使用的 PATH
+const defaultPathEnv = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// containerEnv returns the environment of the container process, the environment of the
+// host is not passed into the container
+func containerEnv(envSlice []string) []string {
+	env := make([]string, 0, len(envSlice)+1)
+	hasPath := false
+	for _, e := range envSlice {
+		if strings.HasPrefix(e, "PATH=") {
+			hasPath = true
+		}
+		env = append(env, e)
+	}
+	if !hasPath {
+		env = append([]string{defaultPathEnv}, env...)
+	}
+	return env
+}
+
+// DefaultMounts returns mounts every container gets, the cgroup mounts are added when
+// the init spec is sent
+func DefaultMounts() []Mount {
+	return []Mount{
+		{
+			// MS_NOEXEC 表示在本文件系统中不允许运行其他程序
+			// MS_NOSUID 表示在本系统中运行程序时，不允许 set-user-ID 或 set-group-ID
+			// MS_NODEV 是所有 mount 会设定的系统默认参数
+			Source:      "proc",
+			Destination: "/proc",
+			Type:        "proc",
+			Flags:       syscall.MS_NOEXEC | syscall.MS_NOSUID | syscall.MS_NODEV,
+		},
+		{
+			Source:      "tmpfs",
+			Destination: "/dev",
+			Type:        "tmpfs",
+			Flags:       syscall.MS_NOSUID | syscall.MS_STRICTATIME,
+			Data:        "mode=755",
+		},
+	}
+}
+
+// 容器内 cgroup 文件系统的挂载点
+const cgroupMountPoint = "/sys/fs/cgroup"
+
+// cgroupMounts returns read-only mounts of the cgroup hierarchies of the host at
+// /sys/fs/cgroup, they are rooted at the container cgroup by the cgroup namespace.
+// They are not stored in the container info, since the cgroup layout of the host may
+// change before the container is started again
+func cgroupMounts() []Mount {
+	flags := syscall.MS_RDONLY | syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC
+	if subsystems.IsCgroup2UnifiedMode() {
+		return []Mount{{Source: "cgroup2", Destination: cgroupMountPoint, Type: "cgroup2", Flags: flags}}
+	}
+	hostMounts, err := subsystems.FindCgroupMounts()
+	if err != nil {
+		log.Warnf("find cgroup mounts failed: %v", err)
+		return nil
+	}
+	// v1 的各个 hierarchy 挂载在 tmpfs 中，挂载完成后将 tmpfs 改为只读
+	mounts := []Mount{{
+		Source:      "tmpfs",
+		Destination: cgroupMountPoint,
+		Type:        "tmpfs",
+		Flags:       syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC,
+		Data:        "mode=755",
+	}}
+	for _, m := range hostMounts {
+		if !strings.HasPrefix(m.MountPoint, cgroupMountPoint+"/") {
+			continue
+		}
+		mount := Mount{Source: m.Type, Destination: m.MountPoint, Type: m.Type, Flags: flags}
+		if m.Type == "cgroup" {
+			// 已有的 hierarchy 只能以相同的 controller 挂载，user namespace 中不允许设置 release_agent
+			var options []string
+			for _, option := range strings.Split(m.Options, ",") {
+				if option != "rw" && option != "ro" && !strings.HasPrefix(option, "release_agent=") {
+					options = append(options, option)
+				}
+			}
+			mount.Data = strings.Join(options, ",")
+		}
+		mounts = append(mounts, mount)
+	}
+	return append(mounts, Mount{
+		Destination: cgroupMountPoint,
+		Flags:       syscall.MS_REMOUNT | flags,
+		Data:        "mode=755",
+	})
+}
+
+// ParseRlimits parses ulimits in the form of type=soft[:hard]
+func ParseRlimits(ulimits []string) ([]Rlimit, error) {
+	rlimits := make([]Rlimit, 0, len(ulimits))
+	for _, ulimit := range ulimits {
+		kv := strings.SplitN(ulimit, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid ulimit %q, should be type=soft[:hard]", ulimit)
+		}
+		if _, ok := rlimitResources[kv[0]]; !ok {
+			return nil, fmt.Errorf("invalid ulimit type %q", kv[0])
+		}
+		limits := strings.SplitN(kv[1], ":", 2)
+		soft, err := parseRlimitValue(limits[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ulimit %q: %v", ulimit, err)
+		}
+		hard := soft
+		if len(limits) == 2 {
+			if hard, err = parseRlimitValue(limits[1]); err != nil {
+				return nil, fmt.Errorf("invalid ulimit %q: %v", ulimit, err)
+			}
+		}
+		if soft > hard {
+			return nil, fmt.Errorf("invalid ulimit %q: soft limit is greater than hard limit", ulimit)
+		}
+		rlimits = append(rlimits, Rlimit{Type: kv[0], Soft: soft, Hard: hard})
+	}
+	return rlimits, nil
+}
+
+func parseRlimitValue(value string) (uint64, error) {
+	if value == "unlimited" || value == "-1" {
+		return unix.RLIM_INFINITY, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// SendInitSpec writes the init spec with the current cgroup mounts of the host to the
+// pipe and closes it
+func SendInitSpec(spec *InitSpec, writePipe *os.File) error {
+	defer writePipe.Close()
+	sent := *spec
+	sent.Mounts = append(append([]Mount{}, spec.Mounts...), cgroupMounts()...)
+	if err := json.NewEncoder(writePipe).Encode(&sent); err != nil {
+		return fmt.Errorf("send init spec failed: %v", err)
+	}
+	return nil
+}
+
+// readInitSpec reads the init spec sent by parent process
+func readInitSpec(pipe io.Reader) (*InitSpec, error) {
+	var spec InitSpec
+	if err := json.NewDecoder(pipe).Decode(&spec); err != nil {
+		return nil, fmt.Errorf("decode init spec failed: %v", err)
+	}
+	if spec.Version != InitSpecVersion {
+		return nil, fmt.Errorf("unsupported init spec version %d, expect %d", spec.Version, InitSpecVersion)
+	}
+	if len(spec.Args) == 0 {
+		return nil, fmt.Errorf("cmd is null")
+	}
+	return &spec, nil
+}
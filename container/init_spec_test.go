@@ -0,0 +1,109 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseRlimits(t *testing.T) {
+	rlimits, err := ParseRlimits([]string{"nofile=1024:2048", "core=unlimited"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rlimits[0] != (Rlimit{Type: "nofile", Soft: 1024, Hard: 2048}) {
+		t.Errorf("unexpected nofile rlimit: %+v", rlimits[0])
+	}
+	if rlimits[1].Soft != unix.RLIM_INFINITY || rlimits[1].Hard != unix.RLIM_INFINITY {
+		t.Errorf("unexpected core rlimit: %+v", rlimits[1])
+	}
+
+	for _, invalid := range []string{"nofile", "foo=1", "nofile=2:1", "nofile=a"} {
+		if _, err := ParseRlimits([]string{invalid}); err == nil {
+			t.Errorf("expect error for ulimit %q", invalid)
+		}
+	}
+}
+
+func TestInitSpecRoundTrip(t *testing.T) {
+	spec, err := NewInitSpec([]string{"sh", "-c", "echo \"hi there\""}, nil, "", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := json.Marshal(spec)
+	got, err := readInitSpec(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Args) != 3 || got.Args[2] != "echo \"hi there\"" || got.Cwd != "/" {
+		t.Errorf("unexpected spec: %+v", got)
+	}
+
+	spec.Version = InitSpecVersion + 1
+	content, _ = json.Marshal(spec)
+	if _, err := readInitSpec(bytes.NewReader(content)); err == nil {
+		t.Error("expect error for unsupported version")
+	}
+}
+
+func TestParseIDFile(t *testing.T) {
+	passwd := "# comment\nroot:x:0:0:root:/root:/bin/sh\nnobody:x:65534:65534::/:/bin/false\n"
+	fields, err := parseIDFile(strings.NewReader(passwd), func(fields []string) bool { return fields[0] == "nobody" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields[2] != "65534" || fields[3] != "65534" {
+		t.Errorf("unexpected entry: %v", fields)
+	}
+	if _, err := parseIDFile(strings.NewReader(passwd), func(fields []string) bool { return fields[0] == "foo" }); err == nil {
+		t.Error("expect error for missing user")
+	}
+}
+
+func TestContainerEnv(t *testing.T) {
+	env := containerEnv([]string{"FOO=bar"})
+	if len(env) != 2 || env[0] != defaultPathEnv || env[1] != "FOO=bar" {
+		t.Errorf("unexpected env %v", env)
+	}
+	if env := containerEnv([]string{"PATH=/bin"}); len(env) != 1 {
+		t.Errorf("expect PATH given by -e to replace the default one, got %v", env)
+	}
+}
+
+func TestSubIDRange(t *testing.T) {
+	subidFile := filepath.Join(t.TempDir(), "subuid")
+	if err := ioutil.WriteFile(subidFile, []byte("someone:1000:10\n4242:100000:65536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	start, count, err := subIDRange(subidFile, 4242)
+	if err != nil || start != 100000 || count != 65536 {
+		t.Errorf("unexpected range %d:%d, err %v", start, count, err)
+	}
+	if _, _, err := subIDRange(subidFile, 4343); err == nil {
+		t.Error("expect error for a user without subordinate ids")
+	}
+}
+
+func TestIDMappings(t *testing.T) {
+	subidFile := filepath.Join(t.TempDir(), "subid")
+	if err := ioutil.WriteFile(subidFile, []byte("4242:100000:65536\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 没有 CAP_SETUID 和 CAP_SETGID 时只能映射自己的 id
+	uidMappings, gidMappings := idMappings(4242, 4242, false, subidFile, subidFile)
+	if len(uidMappings) != 1 || len(gidMappings) != 1 || uidMappings[0].HostID != 4242 || uidMappings[0].Size != 1 {
+		t.Errorf("unexpected mappings %v %v", uidMappings, gidMappings)
+	}
+	uidMappings, gidMappings = idMappings(4242, 4242, true, subidFile, subidFile)
+	if len(uidMappings) != 2 || len(gidMappings) != 2 || uidMappings[1].ContainerID != 1 || uidMappings[1].HostID != 100000 || gidMappings[1].Size != 65536 {
+		t.Errorf("unexpected mappings %v %v", uidMappings, gidMappings)
+	}
+	if uidMappings, _ = idMappings(0, 0, true, subidFile, subidFile); len(uidMappings) != 1 {
+		t.Errorf("unexpected mappings %v without subordinate ids", uidMappings)
+	}
+}
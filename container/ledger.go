@@ -0,0 +1,54 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"syscall"
+)
+
+// withLedger locks a ledger shared by all containers, decodes it into ledger and calls
+// fn, the ledger is written back if write is set and fn succeeds
+func withLedger(name string, write bool, ledger interface{}, fn func() error) error {
+	ledgerPath := path.Join(path.Dir(fmt.Sprintf(DefaultInfoLocation, "")), name)
+	if err := os.MkdirAll(path.Dir(ledgerPath), 0755); err != nil {
+		return err
+	}
+	ledgerFile, err := os.OpenFile(ledgerPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("open %s failed: %v", name, err)
+	}
+	defer ledgerFile.Close()
+	how := syscall.LOCK_SH
+	if write {
+		how = syscall.LOCK_EX
+	}
+	if err := syscall.Flock(int(ledgerFile.Fd()), how); err != nil {
+		return fmt.Errorf("lock %s failed: %v", name, err)
+	}
+
+	content, err := ioutil.ReadAll(ledgerFile)
+	if err != nil {
+		return fmt.Errorf("read %s failed: %v", name, err)
+	}
+	if len(content) > 0 {
+		if err := json.Unmarshal(content, ledger); err != nil {
+			return fmt.Errorf("decode %s failed: %v", name, err)
+		}
+	}
+	if err := fn(); err != nil || !write {
+		return err
+	}
+	if content, err = json.Marshal(ledger); err != nil {
+		return fmt.Errorf("encode %s failed: %v", name, err)
+	}
+	if err := ledgerFile.Truncate(0); err != nil {
+		return fmt.Errorf("write %s failed: %v", name, err)
+	}
+	if _, err := ledgerFile.WriteAt(content, 0); err != nil {
+		return fmt.Errorf("write %s failed: %v", name, err)
+	}
+	return nil
+}
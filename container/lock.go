@@ -0,0 +1,51 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"syscall"
+	"time"
+)
+
+// 等待容器进程的 monitor (或前台运行容器的进程) 在容器运行期间一直持有该文件锁
+const monitorLockName = "monitor.lock"
+
+// LockContainerMonitor takes the monitor lock of the container, the lock is released
+// when the returned file is closed or the process exits
+func LockContainerMonitor(containerName string) (*os.File, error) {
+	lockFile, err := os.OpenFile(path.Join(getContainerInfoDir(containerName), monitorLockName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open monitor lock failed: %v", err)
+	}
+	if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("container %s is monitored by another process", containerName)
+	}
+	return lockFile, nil
+}
+
+// WaitContainerMonitor waits until no process is monitoring the container, that is
+// the container process has exited and its resources have been released
+func WaitContainerMonitor(containerName string, timeout time.Duration) error {
+	lockPath := path.Join(getContainerInfoDir(containerName), monitorLockName)
+	lockFile, err := os.Open(lockPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("open monitor lock failed: %v", err)
+	}
+	defer lockFile.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := syscall.Flock(int(lockFile.Fd()), syscall.LOCK_SH|syscall.LOCK_NB); err == nil {
+			return syscall.Flock(int(lockFile.Fd()), syscall.LOCK_UN)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for container %s to exit", containerName)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,32 @@
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// 未指定 --stop-signal 时 stop 发送的信号
+const DefaultStopSignal = "SIGTERM"
+
+// ParseSignal parses a signal given by number or name, e.g. 9, KILL or SIGKILL
+func ParseSignal(signal string) (syscall.Signal, error) {
+	if num, err := strconv.Atoi(signal); err == nil {
+		if num <= 0 || num > 64 {
+			return 0, fmt.Errorf("invalid signal: %s", signal)
+		}
+		return syscall.Signal(num), nil
+	}
+	name := strings.ToUpper(signal)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return 0, fmt.Errorf("invalid signal: %s", signal)
+	}
+	return sig, nil
+}
@@ -0,0 +1,23 @@
+package container
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	for _, signal := range []string{"9", "KILL", "sigkill", "SIGKILL"} {
+		sig, err := ParseSignal(signal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig != syscall.SIGKILL {
+			t.Errorf("signal %q parsed as %v", signal, sig)
+		}
+	}
+	for _, invalid := range []string{"0", "65", "FOO", ""} {
+		if _, err := ParseSignal(invalid); err == nil {
+			t.Errorf("expect error for signal %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// init 进程从 fd 3 读取 init spec，通过 fd 4 回传各阶段的结果
+const (
+	initSpecFd = 3
+	initSyncFd = 4
+)
+
+// 容器 init 进程的各个初始化阶段
+const (
+	StageSpec     = "spec"
+	StageCgroupns = "cgroupns"
+	StageTimens   = "timens"
+	StageMount    = "mount"
+	StageHostname = "hostname"
+	StageRlimit   = "rlimit"
+	StageCwd      = "cwd"
+	StageUser     = "user"
+	StageLookPath = "lookpath"
+	StageExec     = "exec"
+)
+
+const (
+	syncTypeAck   = "ack"
+	syncTypeError = "error"
+)
+
+// syncMsg 是 init 进程通过 sync pipe 回传给父进程的消息
+type syncMsg struct {
+	Type  string `json:"type"`
+	Stage string `json:"stage"`
+	Error string `json:"error,omitempty"`
+}
+
+// syncWriter is used by the container init process to report each stage
+type syncWriter struct {
+	encoder *json.Encoder
+}
+
+func newSyncWriter(pipe io.Writer) *syncWriter {
+	return &syncWriter{encoder: json.NewEncoder(pipe)}
+}
+
+func (s *syncWriter) ack(stage string) {
+	s.encoder.Encode(&syncMsg{Type: syncTypeAck, Stage: stage})
+}
+
+func (s *syncWriter) fail(stage string, err error) {
+	s.encoder.Encode(&syncMsg{Type: syncTypeError, Stage: stage, Error: err.Error()})
+}
+
+// WaitInitProcess reads the sync pipe until the init process execs the user command.
+// The sync pipe is closed on exec, so EOF after the last stage means success.
+func WaitInitProcess(syncPipe *os.File) error {
+	defer syncPipe.Close()
+	decoder := json.NewDecoder(syncPipe)
+	lastStage := ""
+	for {
+		var msg syncMsg
+		if err := decoder.Decode(&msg); err != nil {
+			if err != io.EOF {
+				return fmt.Errorf("read init sync message failed: %v", err)
+			}
+			break
+		}
+		if msg.Type == syncTypeError {
+			return fmt.Errorf("container init failed at stage %s: %s", msg.Stage, msg.Error)
+		}
+		lastStage = msg.Stage
+	}
+	if lastStage != StageLookPath {
+		if lastStage == "" {
+			return fmt.Errorf("container init exited before reading init spec")
+		}
+		return fmt.Errorf("container init exited unexpectedly after stage %s", lastStage)
+	}
+	return nil
+}
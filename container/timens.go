@@ -0,0 +1,145 @@
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// TimeOffset 是容器 time namespace 中一个时钟相对于 host 的偏移
+type TimeOffset struct {
+	Clock       string `json:"clock"` // monotonic 或 boottime
+	Seconds     int64  `json:"seconds"`
+	Nanoseconds int64  `json:"nanoseconds"` // 0 到 999999999，偏移为负时 Seconds 向下取整
+}
+
+// ParseTimeOffsets parses offsets in the form of clock=offset, e.g. monotonic=+3600s or
+// boottime=-1d, the offset is a duration which can also be given in days
+func ParseTimeOffsets(specs []string) ([]TimeOffset, error) {
+	var offsets []TimeOffset
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid time offset %q: expect <clock>=<offset>", spec)
+		}
+		clock := kv[0]
+		if clock != "monotonic" && clock != "boottime" {
+			return nil, fmt.Errorf("invalid time offset %q: only monotonic and boottime clocks can be offset", spec)
+		}
+		if seen[clock] {
+			return nil, fmt.Errorf("duplicate time offset of clock %s", clock)
+		}
+		seen[clock] = true
+		offset, err := parseOffset(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid time offset %q: %v", spec, err)
+		}
+		seconds, nanoseconds := int64(offset/time.Second), int64(offset%time.Second)
+		if nanoseconds < 0 {
+			seconds--
+			nanoseconds += int64(time.Second)
+		}
+		offsets = append(offsets, TimeOffset{Clock: clock, Seconds: seconds, Nanoseconds: nanoseconds})
+	}
+	return offsets, nil
+}
+
+// parseOffset parses a duration such as +3600s or -1h30m, "d" is accepted for days
+func parseOffset(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.ParseInt(strings.TrimSuffix(value, "d"), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid days %q", value)
+		}
+		// 与 time.ParseDuration 一样，超出 Duration 范围的偏移也超出了内核允许的范围
+		if maxDays := int64(math.MaxInt64 / (24 * time.Hour)); days > maxDays || days < -maxDays {
+			return 0, fmt.Errorf("days %q out of range", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// setUpTimeNamespace creates a new time namespace with the offsets, the namespace is
+// entered by the container process on exec
+func setUpTimeNamespace(offsets []TimeOffset) error {
+	if err := unix.Unshare(unix.CLONE_NEWTIME); err != nil {
+		return fmt.Errorf("unshare time namespace error: %v", err)
+	}
+	offsetsPath, err := timensOffsetsPath()
+	if err != nil {
+		return err
+	}
+	// 偏移只能在有进程进入 time namespace 之前设置
+	lines := make([]string, 0, len(offsets))
+	for _, offset := range offsets {
+		lines = append(lines, fmt.Sprintf("%s %d %d", offset.Clock, offset.Seconds, offset.Nanoseconds))
+	}
+	if err := ioutil.WriteFile(offsetsPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return fmt.Errorf("set time offsets error: %v", err)
+	}
+	return checkTimeOffsets(offsetsPath, offsets)
+}
+
+// timensOffsetsPath returns the timens_offsets file of the calling thread. Older kernels
+// only provide the file under /proc/<pid>, which refers to the thread group leader, so
+// it can be used only when the calling thread is the leader
+func timensOffsetsPath() (string, error) {
+	if _, err := os.Stat("/proc/thread-self/timens_offsets"); err == nil {
+		return "/proc/thread-self/timens_offsets", nil
+	}
+	if tid := unix.Gettid(); tid != unix.Getpid() {
+		return "", fmt.Errorf("thread %d is not the thread group leader, time offsets can not be set", tid)
+	}
+	return "/proc/self/timens_offsets", nil
+}
+
+// VerifyTimeNamespace checks that the container process has entered the time namespace
+// created by init on exec, which older kernels do not do
+func VerifyTimeNamespace(pid int, offsets []TimeOffset) error {
+	timeNs, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/time", pid))
+	if os.IsNotExist(err) {
+		// 容器命令已经退出
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read time namespace of process %d error: %v", pid, err)
+	}
+	childrenNs, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/time_for_children", pid))
+	if err != nil {
+		return fmt.Errorf("read time namespace of process %d error: %v", pid, err)
+	}
+	if timeNs != childrenNs {
+		return fmt.Errorf("container process is still in %s after exec, the kernel does not switch time namespace on exec", timeNs)
+	}
+	return checkTimeOffsets(fmt.Sprintf("/proc/%d/timens_offsets", pid), offsets)
+}
+
+// checkTimeOffsets reads back the timens_offsets file and compares it with the offsets
+func checkTimeOffsets(offsetsPath string, offsets []TimeOffset) error {
+	content, err := ioutil.ReadFile(offsetsPath)
+	if err != nil {
+		return fmt.Errorf("read time offsets error: %v", err)
+	}
+	current := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 {
+			current[fields[0]] = fields[1] + " " + fields[2]
+		}
+	}
+	for _, offset := range offsets {
+		expected := fmt.Sprintf("%d %d", offset.Seconds, offset.Nanoseconds)
+		if current[offset.Clock] != expected {
+			return fmt.Errorf("time offset of clock %s is %q, expect %q", offset.Clock, current[offset.Clock], expected)
+		}
+	}
+	return nil
+}
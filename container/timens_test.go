@@ -0,0 +1,28 @@
+package container
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTimeOffsets(t *testing.T) {
+	offsets, err := ParseTimeOffsets([]string{"monotonic=+3600s", "boottime=-1.5s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []TimeOffset{
+		{Clock: "monotonic", Seconds: 3600},
+		{Clock: "boottime", Seconds: -2, Nanoseconds: 500000000},
+	}
+	if !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("unexpected offsets %+v", offsets)
+	}
+	if offsets, err = ParseTimeOffsets([]string{"boottime=+1d"}); err != nil || offsets[0].Seconds != 86400 {
+		t.Errorf("unexpected offsets %+v, err %v", offsets, err)
+	}
+	for _, invalid := range []string{"realtime=1s", "monotonic", "monotonic=1x", "monotonic=+1d2h", "boottime=106752d", "boottime=-106752d"} {
+		if _, err := ParseTimeOffsets([]string{invalid}); err == nil {
+			t.Errorf("expect error for %q", invalid)
+		}
+	}
+}
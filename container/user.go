@@ -0,0 +1,176 @@
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	passwdPath = "/etc/passwd"
+	groupPath  = "/etc/group"
+	subuidPath = "/etc/subuid"
+	subgidPath = "/etc/subgid"
+)
+
+// IDMappings returns the uid and gid mappings of the container user namespace. Container
+// id 0 is mapped to the current user, ids from 1 are mapped to the subordinate ids of the
+// current user in /etc/subuid and /etc/subgid if there are any and they can be mapped.
+func IDMappings() (uidMappings, gidMappings []syscall.SysProcIDMap) {
+	return idMappings(syscall.Getuid(), syscall.Getgid(), canMapSubIDs(), subuidPath, subgidPath)
+}
+
+func idMappings(uid, gid int, mapSubIDs bool, subuidFile, subgidFile string) (uidMappings, gidMappings []syscall.SysProcIDMap) {
+	uidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: uid, Size: 1}}
+	gidMappings = []syscall.SysProcIDMap{{ContainerID: 0, HostID: gid, Size: 1}}
+	if !mapSubIDs {
+		return uidMappings, gidMappings
+	}
+	if start, count, err := subIDRange(subuidFile, uid); err == nil {
+		uidMappings = append(uidMappings, syscall.SysProcIDMap{ContainerID: 1, HostID: start, Size: count})
+	}
+	if start, count, err := subIDRange(subgidFile, uid); err == nil {
+		gidMappings = append(gidMappings, syscall.SysProcIDMap{ContainerID: 1, HostID: start, Size: count})
+	}
+	return uidMappings, gidMappings
+}
+
+// canMapSubIDs reports whether the current process can write ids other than its own into
+// uid_map and gid_map, which needs CAP_SETUID and CAP_SETGID
+func canMapSubIDs() bool {
+	header := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	var data [2]unix.CapUserData
+	if err := unix.Capget(&header, &data[0]); err != nil {
+		return syscall.Geteuid() == 0
+	}
+	return data[0].Effective&(1<<unix.CAP_SETUID) != 0 && data[0].Effective&(1<<unix.CAP_SETGID) != 0
+}
+
+// subIDRange finds the subordinate ids of the user in files like /etc/subuid, each line
+// is name:start:count and the name can also be the uid
+func subIDRange(filePath string, uid int) (int, int, error) {
+	names := []string{strconv.Itoa(uid)}
+	if entry, err := lookupIDFile(passwdPath, func(fields []string) bool { return len(fields) > 3 && fields[2] == names[0] }); err == nil {
+		names = append(names, entry[0])
+	}
+	entry, err := lookupIDFile(filePath, func(fields []string) bool {
+		return fields[0] == names[0] || len(names) > 1 && fields[0] == names[1]
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	start, err := strconv.Atoi(entry[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid start %q in %s", entry[1], filePath)
+	}
+	count, err := strconv.Atoi(entry[2])
+	if err != nil || count <= 0 {
+		return 0, 0, fmt.Errorf("invalid count %q in %s", entry[2], filePath)
+	}
+	return start, count, nil
+}
+
+// checkUserMapped rejects a non-root user when the user namespace maps only root, the
+// init process would fail to switch to it
+func checkUserMapped(user string) error {
+	if user == "" {
+		return nil
+	}
+	uidMappings, gidMappings := IDMappings()
+	userPart, groupPart := user, ""
+	if i := strings.Index(user, ":"); i >= 0 {
+		userPart, groupPart = user[:i], user[i+1:]
+	}
+	for _, part := range []struct {
+		name     string
+		mappings []syscall.SysProcIDMap
+		file     string
+	}{{userPart, uidMappings, subuidPath}, {groupPart, gidMappings, subgidPath}} {
+		if part.name == "" || part.name == "0" || part.name == "root" {
+			continue
+		}
+		if len(part.mappings) == 1 {
+			return fmt.Errorf("user %s is not root, which needs subordinate ids of the current user in %s and CAP_SETUID and CAP_SETGID to map them", user, part.file)
+		}
+		if id, err := strconv.Atoi(part.name); err == nil && id > part.mappings[1].Size {
+			return fmt.Errorf("id %d of user %s is out of the %d subordinate ids in %s", id, user, part.mappings[1].Size, part.file)
+		}
+	}
+	return nil
+}
+
+// resolveUser resolves user[:group] to uid and gid by the passwd and group files
+// inside the container, numeric ids are used directly
+func resolveUser(user string) (int, int, error) {
+	userPart, groupPart := user, ""
+	if i := strings.Index(user, ":"); i >= 0 {
+		userPart, groupPart = user[:i], user[i+1:]
+	}
+
+	uid, gid := 0, 0
+	if id, err := strconv.Atoi(userPart); err == nil {
+		uid = id
+		// 数字 uid 在 passwd 中存在时使用其默认 gid
+		if entry, err := lookupIDFile(passwdPath, func(fields []string) bool { return len(fields) > 3 && fields[2] == userPart }); err == nil {
+			gid, _ = strconv.Atoi(entry[3])
+		}
+	} else {
+		entry, err := lookupIDFile(passwdPath, func(fields []string) bool { return len(fields) > 3 && fields[0] == userPart })
+		if err != nil {
+			return 0, 0, fmt.Errorf("unable to find user %s: %v", userPart, err)
+		}
+		uid, _ = strconv.Atoi(entry[2])
+		gid, _ = strconv.Atoi(entry[3])
+	}
+
+	if groupPart != "" {
+		if id, err := strconv.Atoi(groupPart); err == nil {
+			gid = id
+		} else {
+			entry, err := lookupIDFile(groupPath, func(fields []string) bool { return fields[0] == groupPart })
+			if err != nil {
+				return 0, 0, fmt.Errorf("unable to find group %s: %v", groupPart, err)
+			}
+			gid, _ = strconv.Atoi(entry[2])
+		}
+	}
+	return uid, gid, nil
+}
+
+func lookupIDFile(filePath string, match func(fields []string) bool) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return parseIDFile(file, match)
+}
+
+// parseIDFile finds the first entry matched in files like /etc/passwd and /etc/group
+func parseIDFile(r io.Reader, match func(fields []string) bool) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// passwd 至少 4 列: name:password:uid:gid, group 至少 3 列: name:password:gid
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 {
+			continue
+		}
+		if match(fields) {
+			return fields, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no matching entries")
+}
@@ -18,14 +18,17 @@ const (
 	WorkURL       = "/home/zyc/testMyDocker/work/%s"
 )
 
-// NewWorkSpace create a overlayfs as container root workspace
-func NewWorkSpace(volume string, imageName string, containerName string) error {
+// CreateWorkSpace creates the read only layer and the write layer of container,
+// the write layer survives until the container is removed
+func CreateWorkSpace(imageName string, containerName string) error {
 	if err := CreateReadOnlyLayer(imageName); err != nil {
 		return err
 	}
-	if err := createWriteLayer(containerName); err != nil {
-		return err
-	}
+	return createWriteLayer(containerName)
+}
+
+// MountWorkSpace mounts a overlayfs as container root workspace
+func MountWorkSpace(volume string, imageName string, containerName string) error {
 	if err := CreateMountPoint(containerName, imageName); err != nil {
 		return err
 	}
@@ -129,7 +132,8 @@ func CreateMountPoint(containerName string, imageName string) error {
 	return nil
 }
 
-func DeleteWorkSpace(volume string, containerName string) {
+// UnmountWorkSpace umounts the volume and the root workspace of container
+func UnmountWorkSpace(volume string, containerName string) {
 	if volume != "" {
 		volumeURLs := strings.Split(volume, ":")
 		if len(volumeURLs) == 2 && volumeURLs[0] != "" && volumeURLs[1] != "" {
@@ -141,6 +145,11 @@ func DeleteWorkSpace(volume string, containerName string) {
 	if err := DeleteMountPoint(containerName); err != nil {
 		log.Error(err)
 	}
+}
+
+// DeleteWorkSpace umounts the workspace and deletes the write layer of container
+func DeleteWorkSpace(volume string, containerName string) {
+	UnmountWorkSpace(volume, containerName)
 	if err := DeleteWriteLayer(containerName); err != nil {
 		log.Error(err)
 	}
@@ -150,6 +159,10 @@ func DeleteVolume(volumeURLs []string, containerName string) error {
 	containerURL := volumeURLs[1]
 	mntURL := fmt.Sprintf(MntURL, containerName)
 	containerVolumeURL := path.Join(mntURL, containerURL)
+	// already released
+	if !util.PathExists(containerVolumeURL) {
+		return nil
+	}
 
 	cmd := exec.Command("umount", containerVolumeURL)
 	cmd.Stdout = os.Stdout
@@ -163,6 +176,10 @@ func DeleteVolume(volumeURLs []string, containerName string) error {
 func DeleteMountPoint(containerName string) error {
 	// first umount
 	mntURL := fmt.Sprintf(MntURL, containerName)
+	// already released
+	if !util.PathExists(mntURL) {
+		return nil
+	}
 	cmd := exec.Command("umount", mntURL)
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("deleteMountPoint umount %s failed: %v", mntURL, err)
@@ -180,5 +197,9 @@ func DeleteWriteLayer(containerName string) error {
 	if err := os.RemoveAll(writeURL); err != nil {
 		return fmt.Errorf("deleteWriteLayer remove dir %s failed: %v", writeURL, err)
 	}
+	workURL := fmt.Sprintf(WorkURL, containerName)
+	if err := os.RemoveAll(workURL); err != nil {
+		return fmt.Errorf("deleteWriteLayer remove workdir %s failed: %v", workURL, err)
+	}
 	return nil
 }
@@ -78,9 +78,38 @@ func (driver *BridgeNetworkDriver) Connect(network *Network, endpoint *Endpoint)
 }
 
 func (driver *BridgeNetworkDriver) Disconnect(network Network, endpoint *Endpoint) error {
+	// 容器的 Net Namespace 销毁时 Veth 会被一并删除
+	veth, err := netlink.LinkByName(endpoint.ID[:5])
+	if err != nil {
+		if _, ok := err.(netlink.LinkNotFoundError); ok {
+			return nil
+		}
+		return fmt.Errorf("bridgeDisconnect failed: %v", err)
+	}
+	if err := netlink.LinkDel(veth); err != nil {
+		return fmt.Errorf("bridgeDisconnect failed: %v", err)
+	}
 	return nil
 }
 
+// Stats reads the counters of the veth on the host, which receives what the container sends
+func (driver *BridgeNetworkDriver) Stats(endpoint *Endpoint) (*EndpointStats, error) {
+	veth, err := netlink.LinkByName(endpoint.ID[:5])
+	if err != nil {
+		return nil, fmt.Errorf("bridgeStats failed: %v", err)
+	}
+	statistics := veth.Attrs().Statistics
+	if statistics == nil {
+		return nil, fmt.Errorf("bridgeStats failed: no statistics of %s", endpoint.ID[:5])
+	}
+	return &EndpointStats{
+		RxBytes:   statistics.TxBytes,
+		TxBytes:   statistics.RxBytes,
+		RxPackets: statistics.TxPackets,
+		TxPackets: statistics.RxPackets,
+	}, nil
+}
+
 // initBridge inits dev bridge
 func (driver *BridgeNetworkDriver) initBridge(n *Network) error {
 	// 1. create bridge
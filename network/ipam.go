@@ -88,7 +88,9 @@ func (ipam *IPAM) Allocate(subnet *net.IPNet) (net.IP, error) {
 	if !ok {
 		return nil, fmt.Errorf("allocate failed: no available ip in network segment")
 	}
-	ip := subnet.IP
+	// 复制一份，避免修改 subnet 本身; 从文件加载的 IP 为 16 字节形式
+	ip := make(net.IP, net.IPv4len)
+	copy(ip, subnet.IP.To4())
 	for i := 3; i >= 0; i-- {
 		[]byte(ip)[3-i] += uint8(index >> (i * 8))
 	}
@@ -106,16 +108,17 @@ func (ipam *IPAM) Release(subnet *net.IPNet, ipAddr *net.IP) error {
 	if err != nil {
 		return fmt.Errorf("allocate failed: %v", err)
 	}
-	releaseIP := ipAddr.To4()
+	releaseIP := make(net.IP, net.IPv4len)
+	copy(releaseIP, ipAddr.To4())
 	releaseIP[3]--
+	subnetIP := subnet.IP.To4()
 	index := 0
 	for i := 3; i >= 0; i-- {
-		index += int((releaseIP[i] - subnet.IP[i]) << ((3 - i) * 8))
+		index += int(releaseIP[i]-subnetIP[i]) << ((3 - i) * 8)
 	}
 	if _, exist := (*ipam.Subnets)[subnet.String()]; !exist {
 		return fmt.Errorf("released ip not in ipam configfile")
 	}
-	println(index)
 	(*ipam.Subnets)[subnet.String()].Remove(uint64(index))
 
 	if err := ipam.dump(); err != nil {
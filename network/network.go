@@ -48,6 +48,15 @@ type NetworkDriver interface {
 	Delete(network Network) error                         // 删除网络
 	Connect(network *Network, endpoint *Endpoint) error   // 连接容器网络端点到网络
 	Disconnect(network Network, endpoint *Endpoint) error // 从网络上移除容器网络端点
+	Stats(endpoint *Endpoint) (*EndpointStats, error)     // 读取容器网络端点的流量统计
+}
+
+// EndpointStats 是容器网络端点的流量统计，收发方向以容器为准
+type EndpointStats struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
 }
 
 func CreateNetwork(driver string, subnet string, name string) error {
@@ -179,8 +188,9 @@ func DeleteNetwork(networkName string) error {
 	return network.remove(defaultNetworkPath)
 }
 
-//
-func Connect(networkName string, cinfo *container.ContainerInfo) error {
+// Connect connects the container to the network, resources already created
+// are released if any step fails
+func Connect(networkName string, cinfo *container.ContainerInfo) (err error) {
 	network, ok := networks[networkName]
 	if !ok {
 		return fmt.Errorf("no suck network: %s", networkName)
@@ -192,6 +202,13 @@ func Connect(networkName string, cinfo *container.ContainerInfo) error {
 		return err
 	}
 	log.Infof("allocated ip is %v", ip)
+	defer func() {
+		if err != nil {
+			if releaseErr := ipamDefaultAllocator.Release(network.IPRange, &ip); releaseErr != nil {
+				log.Errorf("release ip %v failed: %v", ip, releaseErr)
+			}
+		}
+	}()
 
 	// 创建网络端点
 	endpoint := &Endpoint{
@@ -205,6 +222,13 @@ func Connect(networkName string, cinfo *container.ContainerInfo) error {
 	if err = drivers[network.Driver].Connect(network, endpoint); err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			if disconnectErr := drivers[network.Driver].Disconnect(*network, endpoint); disconnectErr != nil {
+				log.Errorf("remove endpoint %s failed: %v", endpoint.ID, disconnectErr)
+			}
+		}
+	}()
 
 	// 到容器的Namespace中配置容器网络、设备IP地址和路由信息
 	if err = configEndpointIPAddressAndRoute(endpoint, cinfo); err != nil {
@@ -212,7 +236,37 @@ func Connect(networkName string, cinfo *container.ContainerInfo) error {
 	}
 
 	// 配置端口映射信息
-	return configPortMapping(endpoint, cinfo)
+	if err = configPortMapping(endpoint, cinfo); err != nil {
+		return err
+	}
+	cinfo.IPAddress = ip.String()
+	return nil
+}
+
+// Disconnect removes the network endpoint of the container and releases its ip
+func Disconnect(networkName string, cinfo *container.ContainerInfo) error {
+	network, ok := networks[networkName]
+	if !ok {
+		return fmt.Errorf("no suck network: %s", networkName)
+	}
+	ip := net.ParseIP(cinfo.IPAddress)
+	if ip == nil {
+		return fmt.Errorf("invalid container ip %q", cinfo.IPAddress)
+	}
+
+	endpoint := &Endpoint{
+		ID:          fmt.Sprintf("%s-%s", cinfo.ID, networkName),
+		IPAddress:   ip,
+		Network:     network,
+		PortMapping: cinfo.PortMapping,
+	}
+	if err := deletePortMapping(endpoint); err != nil {
+		return err
+	}
+	if err := drivers[network.Driver].Disconnect(*network, endpoint); err != nil {
+		return err
+	}
+	return ipamDefaultAllocator.Release(network.IPRange, &ip)
 }
 
 //配置容器网络端点的地址和路由
@@ -246,7 +300,7 @@ func configEndpointIPAddressAndRoute(endpoint *Endpoint, cinfo *container.Contai
 	}
 
 	//设置容器内的外部请求都通过Veth端点进行访问
-	_, cidr, _ := net.ParseCIDR("::/0")
+	_, cidr, _ := net.ParseCIDR("0.0.0.0/0")
 	fmt.Println(cidr.IP.String())
 	fmt.Println(endpoint.Network.IPRange.IP.String())
 
@@ -265,21 +319,43 @@ func configEndpointIPAddressAndRoute(endpoint *Endpoint, cinfo *container.Contai
 
 // 配置宿主机到容器的端口映射
 func configPortMapping(endpoint *Endpoint, cinfo *container.ContainerInfo) error {
-	for _, pm := range endpoint.PortMapping {
-		portMapping := strings.Split(pm, ":")
-		if len(portMapping) != 2 {
-			return fmt.Errorf("invalid port mapping format")
+	for i, pm := range endpoint.PortMapping {
+		if err := setPortMapping("-A", endpoint.IPAddress, pm); err != nil {
+			// 删除已经添加的规则
+			for _, added := range endpoint.PortMapping[:i] {
+				setPortMapping("-D", endpoint.IPAddress, added)
+			}
+			return err
 		}
-		iptablesCmd := fmt.Sprintf("-t nat -A PREROUTING -p tcp -m tcp --dport %s -j DNAT --to-destination %s %s", portMapping[0], endpoint.IPAddress.String(), portMapping[1])
-		cmd := exec.Command("iptables", strings.Split(iptablesCmd, " ")...)
-		output, err := cmd.Output()
-		if err != nil {
-			return fmt.Errorf("iptables error: %v", output)
+	}
+	return nil
+}
+
+// 删除宿主机到容器的端口映射
+func deletePortMapping(endpoint *Endpoint) error {
+	for _, pm := range endpoint.PortMapping {
+		if err := setPortMapping("-D", endpoint.IPAddress, pm); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// setPortMapping appends(-A) or deletes(-D) the DNAT rule of a port mapping
+func setPortMapping(action string, ip net.IP, pm string) error {
+	portMapping := strings.Split(pm, ":")
+	if len(portMapping) != 2 {
+		return fmt.Errorf("invalid port mapping format")
+	}
+	iptablesCmd := fmt.Sprintf("-t nat %s PREROUTING -p tcp -m tcp --dport %s -j DNAT --to-destination %s:%s", action, portMapping[0], ip.String(), portMapping[1])
+	cmd := exec.Command("iptables", strings.Split(iptablesCmd, " ")...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("iptables error: %s", output)
+	}
+	return nil
+}
+
 // 将容器的网络端点加入到容器的网络空间中去
 // 并锁定当前程序执行的线程，使当前线程加入到容器的网络空间
 func enterContainerNetns(enLink *netlink.Link, cinfo *container.ContainerInfo) func() {
@@ -320,3 +396,16 @@ func enterContainerNetns(enLink *netlink.Link, cinfo *container.ContainerInfo) f
 		f.Close()
 	}
 }
+
+// GetEndpointStats reads the traffic counters of the container endpoint in the network
+func GetEndpointStats(networkName string, cinfo *container.ContainerInfo) (*EndpointStats, error) {
+	network, ok := networks[networkName]
+	if !ok {
+		return nil, fmt.Errorf("no suck network: %s", networkName)
+	}
+	endpoint := &Endpoint{
+		ID:      fmt.Sprintf("%s-%s", cinfo.ID, networkName),
+		Network: network,
+	}
+	return drivers[network.Driver].Stats(endpoint)
+}
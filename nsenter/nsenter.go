@@ -26,8 +26,9 @@ __attribute__((constructor)) void enter_namespace(void) {
 	}
 	int i;
 	char nspath[1024];
-	char *namespaces[] = { "ipc", "uts", "net", "pid", "mnt" };
-	for (i=0; i<5; i++) {
+	// cgroup 和 time namespace 由容器 init 进程创建，mnt 需要最后加入，否则之后无法打开宿主机的 /proc
+	char *namespaces[] = { "ipc", "uts", "net", "pid", "cgroup", "time", "mnt" };
+	for (i=0; i<7; i++) {
 		sprintf(nspath, "/proc/%s/ns/%s", mydocker_pid, namespaces[i]);
 		int fd = open(nspath, O_RDONLY);
 		if (setns(fd, 0) == -1) {
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+}
+
+// RAMInBytes parses a human-readable size such as 512m, 2g or 1.5GB into bytes, the
+// units are binary and case-insensitive
+func RAMInBytes(size string) (int64, error) {
+	str := strings.ToLower(strings.TrimSpace(size))
+	i := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	numStr, unit := str, ""
+	if i >= 0 {
+		numStr, unit = str[:i], str[i:]
+	}
+	multiplier, ok := sizeUnits[unit]
+	if !ok || numStr == "" {
+		return 0, fmt.Errorf("invalid size: %q", size)
+	}
+	num, err := strconv.ParseFloat(numStr, 64)
+	if err != nil || num < 0 {
+		return 0, fmt.Errorf("invalid size: %q", size)
+	}
+	return int64(num * float64(multiplier)), nil
+}
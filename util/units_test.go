@@ -0,0 +1,28 @@
+package util
+
+import "testing"
+
+func TestRAMInBytes(t *testing.T) {
+	cases := map[string]int64{
+		"1024": 1024,
+		"10b":  10,
+		"512m": 512 << 20,
+		"512M": 512 << 20,
+		"2g":   2 << 30,
+		"2GB":  2 << 30,
+		"1.5k": 1536,
+	}
+	for size, expected := range cases {
+		got, err := RAMInBytes(size)
+		if err != nil {
+			t.Errorf("RAMInBytes(%q) failed: %v", size, err)
+		} else if got != expected {
+			t.Errorf("RAMInBytes(%q) = %d, want %d", size, got, expected)
+		}
+	}
+	for _, invalid := range []string{"", "m", "-1", "12x", "1.2.3m", "10mbb"} {
+		if _, err := RAMInBytes(invalid); err == nil {
+			t.Errorf("expect error for size %q", invalid)
+		}
+	}
+}